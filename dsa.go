@@ -0,0 +1,181 @@
+package cryptopals
+
+import (
+	"crypto/rand"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// mustInt parses s in the given base and panics if it is not a valid integer.
+func mustInt(s string, base int) *big.Int {
+	i, ok := new(big.Int).SetString(s, base)
+	if !ok {
+		panic("invalid integer: " + s)
+	}
+	return i
+}
+
+// DSAParameters are the DSA domain parameters.
+type DSAParameters struct {
+	P, Q, G *big.Int
+}
+
+// ChallengeDSAParameters are the domain parameters used throughout the DSA challenges.
+var ChallengeDSAParameters = DSAParameters{
+	P: mustInt("800000000000000089e1855218a0e7dac38136ffafa72eda7859f2171e25e65eac698c1702578b07dc2a1076da241c76c62d374d8389ea5aeffd3226a0530cc565f3bf6b50929139ebeac04f48c3c84afb796d61e5a4f9a8fda812ab59494232c7d2b4deb50aa18ee9e132bfa85ac4374d7f9091abc3d015efc871a584471bb1", 16),
+	Q: mustInt("f4f47f05794b256174bba6e9b396a7707e563c5b", 16),
+	G: mustInt("5958c9d3898b224b12672c0b98e06c60df923cb8bc999d119458fef538b8fa4046c8db53039db620c094c9fa077ef389b5322a559946a71903f990f1f7e0e025e2d7f7cf494aff1a0470f5b64c36b625a097f1651fe775323556fe00b3608c887892878480e99041be601a62166ca6894bdd41a7054ec89f756ba9fc95302291", 16),
+}
+
+// DSAPublicKey is a DSA public key.
+type DSAPublicKey struct {
+	DSAParameters
+	Y *big.Int
+}
+
+// DSAPrivateKey is a DSA private key.
+type DSAPrivateKey struct {
+	DSAPublicKey
+	X *big.Int
+}
+
+// GenerateDSAKey generates a key pair for the given domain parameters.
+func GenerateDSAKey(params DSAParameters) (*DSAPrivateKey, error) {
+	x, err := randRange(bigOne, params.Q)
+	if err != nil {
+		return nil, err
+	}
+	return &DSAPrivateKey{
+		DSAPublicKey: DSAPublicKey{
+			DSAParameters: params,
+			Y:             new(big.Int).Exp(params.G, x, params.P),
+		},
+		X: x,
+	}, nil
+}
+
+// randRange returns a uniformly random integer in [lo, hi).
+func randRange(lo, hi *big.Int) (*big.Int, error) {
+	n, err := rand.Int(rand.Reader, new(big.Int).Sub(hi, lo))
+	if err != nil {
+		return nil, err
+	}
+	return n.Add(n, lo), nil
+}
+
+// hashToInt converts a hash to an integer, keeping the leftmost bits when it is longer than q.
+func hashToInt(hashed []byte, q *big.Int) *big.Int {
+	n := (q.BitLen() + 7) / 8
+	if len(hashed) > n {
+		hashed = hashed[:n]
+	}
+	h := new(big.Int).SetBytes(hashed)
+	if excess := len(hashed)*8 - q.BitLen(); excess > 0 {
+		h.Rsh(h, uint(excess))
+	}
+	return h
+}
+
+// ErrInvalidDSAParameters is returned when signing with domain parameters that VerifyDSA would reject.
+var ErrInvalidDSAParameters = errors.New("invalid DSA parameters")
+
+// maxDSASignAttempts bounds the number of nonces Sign tries before giving up on a zero r or s.
+const maxDSASignAttempts = 32
+
+// valid reports whether q is positive and g lies in (1, p).
+func (params DSAParameters) valid() bool {
+	return params.Q.Sign() > 0 && params.G.Cmp(bigOne) > 0 && params.G.Cmp(params.P) < 0
+}
+
+// Sign signs hashed with a random nonce, retrying if r or s comes out zero.
+func (k *DSAPrivateKey) Sign(hashed []byte) (r, s *big.Int, err error) {
+	if !k.valid() {
+		return nil, nil, ErrInvalidDSAParameters
+	}
+	for i := 0; i < maxDSASignAttempts; i++ {
+		nonce, err := randRange(bigOne, k.Q)
+		if err != nil {
+			return nil, nil, err
+		}
+		r, s, err = k.SignWithNonce(hashed, nonce)
+		if err != nil {
+			return nil, nil, err
+		}
+		if r.Sign() != 0 && s.Sign() != 0 {
+			return r, s, nil
+		}
+	}
+	return nil, nil, errors.Errorf("r or s was zero for %d nonces", maxDSASignAttempts)
+}
+
+// SignWithNonce signs hashed using the caller-provided nonce k. Reusing or leaking the nonce reveals the private key.
+// Unlike Sign it does not check the parameters or reject a zero r or s.
+func (k *DSAPrivateKey) SignWithNonce(hashed []byte, nonce *big.Int) (r, s *big.Int, err error) {
+	kinv, err := InvMod(nonce, k.Q)
+	if err != nil {
+		return nil, nil, err
+	}
+	// r = (g**k mod p) mod q
+	r = new(big.Int).Exp(k.G, nonce, k.P)
+	r.Mod(r, k.Q)
+	// s = k**-1 (H(m) + xr) mod q
+	s = new(big.Int).Mul(k.X, r)
+	s.Add(s, hashToInt(hashed, k.Q))
+	s.Mul(s, kinv).Mod(s, k.Q)
+	return r, s, nil
+}
+
+// VerifyDSA reports whether (r, s) is a valid signature of hashed under pub.
+// The generator and the signature values are range checked before verification.
+func VerifyDSA(pub *DSAPublicKey, hashed []byte, r, s *big.Int) bool {
+	if !pub.valid() {
+		return false
+	}
+	if r.Sign() <= 0 || r.Cmp(pub.Q) >= 0 || s.Sign() <= 0 || s.Cmp(pub.Q) >= 0 {
+		return false
+	}
+	return VerifyDSANaive(pub, hashed, r, s)
+}
+
+// VerifyDSANaive verifies (r, s) like VerifyDSA but trusts the caller-supplied domain parameters
+// and skips all range checks.
+func VerifyDSANaive(pub *DSAPublicKey, hashed []byte, r, s *big.Int) bool {
+	w, err := InvMod(s, pub.Q)
+	if err != nil {
+		return false
+	}
+	// u1 = H(m) w mod q, u2 = r w mod q, v = (g**u1 y**u2 mod p) mod q
+	u1 := new(big.Int).Mul(hashToInt(hashed, pub.Q), w)
+	u1.Mod(u1, pub.Q)
+	u2 := new(big.Int).Mul(r, w)
+	u2.Mod(u2, pub.Q)
+	v := new(big.Int).Exp(pub.G, u1, pub.P)
+	v.Mul(v, new(big.Int).Exp(pub.Y, u2, pub.P)).Mod(v, pub.P)
+	v.Mod(v, pub.Q)
+	return v.Cmp(r) == 0
+}
+
+// DSAPrivateKeyFromNonce recovers the private key from a signature whose nonce k is known:
+// x = (s k - H(m)) r**-1 mod q. It returns ErrNotFound if the result does not match pub.
+func DSAPrivateKeyFromNonce(pub *DSAPublicKey, hashed []byte, r, s, k *big.Int) (*DSAPrivateKey, error) {
+	x, err := dsaXFromNonce(&pub.DSAParameters, hashed, r, s, k)
+	if err != nil {
+		return nil, err
+	}
+	if new(big.Int).Exp(pub.G, x, pub.P).Cmp(pub.Y) != 0 {
+		return nil, ErrNotFound
+	}
+	return &DSAPrivateKey{DSAPublicKey: *pub, X: x}, nil
+}
+
+// dsaXFromNonce returns x = (s k - H(m)) r**-1 mod q without checking it against a public key.
+func dsaXFromNonce(params *DSAParameters, hashed []byte, r, s, k *big.Int) (*big.Int, error) {
+	rinv, err := InvMod(r, params.Q)
+	if err != nil {
+		return nil, err
+	}
+	x := new(big.Int).Mul(s, k)
+	x.Sub(x, hashToInt(hashed, params.Q))
+	return x.Mul(x, rinv).Mod(x, params.Q), nil
+}
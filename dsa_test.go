@@ -0,0 +1,76 @@
+package cryptopals
+
+import (
+	"crypto/sha1"
+	"math/big"
+	"testing"
+)
+
+func TestDSA(t *testing.T) {
+	k, err := GenerateDSAKey(ChallengeDSAParameters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed := sha1.Sum([]byte("sign me"))
+	r, s, err := k.Sign(hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		hashed []byte
+		r, s   *big.Int
+		want   bool
+	}{
+		{"valid", hashed[:], r, s, true},
+		{"wrong message", []byte("something else"), r, s, false},
+		{"wrong r", hashed[:], new(big.Int).Add(r, bigOne), s, false},
+		{"zero s", hashed[:], r, new(big.Int), false},
+		{"r out of range", hashed[:], new(big.Int).Add(r, k.Q), s, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyDSA(&k.DSAPublicKey, tt.hashed, tt.r, tt.s); got != tt.want {
+				t.Errorf("VerifyDSA() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDSASignInvalidParameters(t *testing.T) {
+	for _, g := range []*big.Int{big.NewInt(0), bigOne, ChallengeDSAParameters.P} {
+		params := ChallengeDSAParameters
+		params.G = g
+		k, err := GenerateDSAKey(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashed := sha1.Sum([]byte("sign me"))
+		if _, _, err := k.Sign(hashed[:]); err != ErrInvalidDSAParameters {
+			t.Errorf("Sign() with g = %v: error = %v, want %v", g, err, ErrInvalidDSAParameters)
+		}
+	}
+}
+
+func TestDSAPrivateKeyFromNonce(t *testing.T) {
+	k, err := GenerateDSAKey(ChallengeDSAParameters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed := sha1.Sum([]byte("sign me"))
+	nonce := big.NewInt(31337)
+	r, s, err := k.SignWithNonce(hashed[:], nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := DSAPrivateKeyFromNonce(&k.DSAPublicKey, hashed[:], r, s, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.X.Cmp(k.X) != 0 {
+		t.Errorf("DSAPrivateKeyFromNonce() = %v, want %v", got.X, k.X)
+	}
+	if _, err := DSAPrivateKeyFromNonce(&k.DSAPublicKey, hashed[:], r, s, big.NewInt(31338)); err != ErrNotFound {
+		t.Errorf("DSAPrivateKeyFromNonce() error = %v, want %v", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,413 @@
+package cryptopals
+
+import (
+	"crypto/rand"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrInvalidPoint is returned when a point is not on the expected curve.
+	ErrInvalidPoint = errors.New("point not on curve")
+	// ErrInvalidCurve is returned for curve parameters that do not define an elliptic curve over a prime field.
+	ErrInvalidCurve = errors.New("invalid curve")
+)
+
+// ECPoint is an affine point on an elliptic curve. The point at infinity has nil coordinates.
+type ECPoint struct {
+	X, Y *big.Int
+}
+
+// NewECPoint returns the point (x, y).
+func NewECPoint(x, y int64) *ECPoint {
+	return &ECPoint{X: big.NewInt(x), Y: big.NewInt(y)}
+}
+
+// IsIdentity reports whether p is the point at infinity.
+func (p *ECPoint) IsIdentity() bool {
+	return p.X == nil
+}
+
+// Equal reports whether p and q are the same point.
+func (p *ECPoint) Equal(q *ECPoint) bool {
+	if p.IsIdentity() || q.IsIdentity() {
+		return p.IsIdentity() == q.IsIdentity()
+	}
+	return p.X.Cmp(q.X) == 0 && p.Y.Cmp(q.Y) == 0
+}
+
+// WeierstrassCurve is the curve y^2 = x^3 + ax + b over GF(P), with a base point G of order N.
+type WeierstrassCurve struct {
+	A, B, P *big.Int
+	G       *ECPoint
+	N       *big.Int
+}
+
+// Challenge59Curve is the curve y^2 = x^3 - 95051x + 11279326 used in challenge 59.
+var Challenge59Curve = &WeierstrassCurve{
+	A: big.NewInt(-95051),
+	B: big.NewInt(11279326),
+	P: mustInt("233970423115425145524320034830162017933", 10),
+	G: &ECPoint{X: big.NewInt(182), Y: mustInt("85518893674295321206118380980485522083", 10)},
+	N: mustInt("29246302889428143187362802287225875743", 10),
+}
+
+// Validate checks that p is a prime above 3 and that the discriminant 4a^3 + 27b^2 is nonzero mod p.
+// The arithmetic methods assume a valid curve and panic if p is not prime.
+func (c *WeierstrassCurve) Validate() error {
+	if c.P.Cmp(big.NewInt(3)) <= 0 || !c.P.ProbablyPrime(20) {
+		return errors.Wrap(ErrInvalidCurve, "p is not a prime above 3")
+	}
+	d := new(big.Int).Exp(c.A, big.NewInt(3), c.P)
+	d.Lsh(d, 2)
+	b2 := new(big.Int).Mul(c.B, c.B)
+	d.Add(d, b2.Mul(b2, big.NewInt(27))).Mod(d, c.P)
+	if d.Sign() == 0 {
+		return errors.Wrap(ErrInvalidCurve, "singular curve")
+	}
+	return nil
+}
+
+// WithB returns a copy of c with b replaced and no base point.
+func (c *WeierstrassCurve) WithB(b *big.Int) *WeierstrassCurve {
+	return &WeierstrassCurve{A: c.A, B: b, P: c.P}
+}
+
+// Identity returns the point at infinity.
+func (c *WeierstrassCurve) Identity() *ECPoint {
+	return &ECPoint{}
+}
+
+// rhs returns x^3 + ax + b mod p.
+func (c *WeierstrassCurve) rhs(x *big.Int) *big.Int {
+	r := new(big.Int).Mul(x, x)
+	r.Add(r, c.A).Mul(r, x).Add(r, c.B)
+	return r.Mod(r, c.P)
+}
+
+// IsOnCurve reports whether p lies on the curve.
+func (c *WeierstrassCurve) IsOnCurve(p *ECPoint) bool {
+	if p.IsIdentity() {
+		return true
+	}
+	if p.X.Sign() < 0 || p.X.Cmp(c.P) >= 0 || p.Y.Sign() < 0 || p.Y.Cmp(c.P) >= 0 {
+		return false
+	}
+	y2 := new(big.Int).Mul(p.Y, p.Y)
+	return y2.Mod(y2, c.P).Cmp(c.rhs(p.X)) == 0
+}
+
+// Neg returns -p.
+func (c *WeierstrassCurve) Neg(p *ECPoint) *ECPoint {
+	if p.IsIdentity() {
+		return p
+	}
+	y := new(big.Int).Neg(p.Y)
+	return &ECPoint{X: new(big.Int).Set(p.X), Y: y.Mod(y, c.P)}
+}
+
+// reduce returns v mod p, without allocating if v is already reduced.
+func (c *WeierstrassCurve) reduce(v *big.Int) *big.Int {
+	if v.Sign() >= 0 && v.Cmp(c.P) < 0 {
+		return v
+	}
+	return new(big.Int).Mod(v, c.P)
+}
+
+// Add returns p1 + p2. It never uses b, so it also adds points on curves that differ only in b.
+// Coordinates are reduced mod p first, so points need not be on the curve, but p must be prime: Add panics
+// if a slope has no inverse.
+func (c *WeierstrassCurve) Add(p1, p2 *ECPoint) *ECPoint {
+	switch {
+	case p1.IsIdentity():
+		return p2
+	case p2.IsIdentity():
+		return p1
+	}
+	x1, y1, x2, y2 := c.reduce(p1.X), c.reduce(p1.Y), c.reduce(p2.X), c.reduce(p2.Y)
+	if x1.Cmp(x2) == 0 && (y1.Cmp(y2) != 0 || y1.Sign() == 0) {
+		// p2 = -p1, including points of order 2.
+		return c.Identity()
+	}
+	m, d := new(big.Int), new(big.Int)
+	if x1.Cmp(x2) == 0 {
+		// m = (3x^2 + a) / 2y
+		m.Mul(x1, x1).Mul(m, big.NewInt(3)).Add(m, c.A)
+		d.Lsh(y1, 1)
+	} else {
+		// m = (y2 - y1) / (x2 - x1)
+		m.Sub(y2, y1)
+		d.Sub(x2, x1).Mod(d, c.P)
+	}
+	if d.ModInverse(d, c.P) == nil {
+		panic("cryptopals: WeierstrassCurve.Add: slope has no inverse; p is not prime")
+	}
+	m.Mul(m, d).Mod(m, c.P)
+	x := new(big.Int).Mul(m, m)
+	x.Sub(x, x1).Sub(x, x2).Mod(x, c.P)
+	y := new(big.Int).Sub(x1, x)
+	y.Mul(y, m).Sub(y, y1).Mod(y, c.P)
+	return &ECPoint{X: x, Y: y}
+}
+
+// Double returns 2p.
+func (c *WeierstrassCurve) Double(p *ECPoint) *ECPoint {
+	return c.Add(p, p)
+}
+
+// ScalarMult returns kp, using double-and-add. Negative k multiplies -p.
+func (c *WeierstrassCurve) ScalarMult(p *ECPoint, k *big.Int) *ECPoint {
+	if k.Sign() < 0 {
+		return c.ScalarMult(c.Neg(p), new(big.Int).Neg(k))
+	}
+	r := c.Identity()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		r = c.Double(r)
+		if k.Bit(i) == 1 {
+			r = c.Add(r, p)
+		}
+	}
+	return r
+}
+
+// RandomPoint returns a random point on the curve other than the identity.
+func (c *WeierstrassCurve) RandomPoint() (*ECPoint, error) {
+	for {
+		x, err := rand.Int(rand.Reader, c.P)
+		if err != nil {
+			return nil, err
+		}
+		y := new(big.Int).ModSqrt(c.rhs(x), c.P)
+		if y == nil {
+			continue
+		}
+		return &ECPoint{X: x, Y: y}, nil
+	}
+}
+
+// PointOfOrder returns a random point of prime order r on a curve with order points, where r divides order.
+// The group need not be cyclic, so the cofactor is cleared of every power of r before stepping down to order r.
+func (c *WeierstrassCurve) PointOfOrder(order, r *big.Int) (*ECPoint, error) {
+	h, rem := new(big.Int), new(big.Int)
+	if rem.Rem(order, r).Sign() != 0 {
+		return nil, errors.Errorf("%v does not divide the curve order", r)
+	}
+	for h.Set(order); rem.Rem(h, r).Sign() == 0; {
+		h.Quo(h, r)
+	}
+	for {
+		p, err := c.RandomPoint()
+		if err != nil {
+			return nil, err
+		}
+		if p = c.ScalarMult(p, h); p.IsIdentity() {
+			continue
+		}
+		for q := c.ScalarMult(p, r); !q.IsIdentity(); q = c.ScalarMult(p, r) {
+			p = q
+		}
+		return p, nil
+	}
+}
+
+// GenerateECKey returns a random private key in [1, N) and the matching public key.
+func (c *WeierstrassCurve) GenerateECKey() (d *big.Int, pub *ECPoint, err error) {
+	d, err = randRange(bigOne, c.N)
+	if err != nil {
+		return nil, nil, err
+	}
+	return d, c.ScalarMult(c.G, d), nil
+}
+
+// Mul returns a + b, so that a WeierstrassCurve is a Group of *ECPoint.
+func (c *WeierstrassCurve) Mul(a, b GroupElement) GroupElement {
+	return c.Add(a.(*ECPoint), b.(*ECPoint))
+}
+
+// Exp returns ka.
+func (c *WeierstrassCurve) Exp(a GroupElement, k *big.Int) GroupElement {
+	return c.ScalarMult(a.(*ECPoint), k)
+}
+
+// Equal reports whether a and b are the same point.
+func (c *WeierstrassCurve) Equal(a, b GroupElement) bool {
+	return a.(*ECPoint).Equal(b.(*ECPoint))
+}
+
+// Hash returns the low 64 bits of the x coordinate.
+func (c *WeierstrassCurve) Hash(a GroupElement) uint64 {
+	p := a.(*ECPoint)
+	if p.IsIdentity() {
+		return 0
+	}
+	return ModPGroup{}.Hash(p.X)
+}
+
+// MontgomeryCurve is the curve Bv^2 = u^3 + Au^2 + u over GF(P), with a base point (U, V) of order N
+// and cofactor H.
+type MontgomeryCurve struct {
+	A, B, P *big.Int
+	U, V    *big.Int
+	N, H    *big.Int
+}
+
+// Challenge60Curve is the curve v^2 = u^3 + 534u^2 + u used in challenge 60, equivalent to Challenge59Curve.
+var Challenge60Curve = &MontgomeryCurve{
+	A: big.NewInt(534),
+	B: big.NewInt(1),
+	P: mustInt("233970423115425145524320034830162017933", 10),
+	U: big.NewInt(4),
+	V: mustInt("85518893674295321206118380980485522083", 10),
+	N: mustInt("29246302889428143187362802287225875743", 10),
+	H: big.NewInt(8),
+}
+
+// Order returns the number of points on the curve.
+func (c *MontgomeryCurve) Order() *big.Int {
+	return new(big.Int).Mul(c.N, c.H)
+}
+
+// TwistOrder returns the number of points on the quadratic twist, 2p + 2 minus the order of the curve.
+func (c *MontgomeryCurve) TwistOrder() *big.Int {
+	t := new(big.Int).Lsh(c.P, 1)
+	t.Add(t, big.NewInt(2))
+	return t.Sub(t, c.Order())
+}
+
+// rhs returns (u^3 + Au^2 + u) / B mod p.
+func (c *MontgomeryCurve) rhs(u *big.Int) *big.Int {
+	r := new(big.Int).Add(u, c.A)
+	r.Mul(r, u).Add(r, bigOne).Mul(r, u)
+	r.Mul(r, c.inv(c.B))
+	return r.Mod(r, c.P)
+}
+
+// Validate checks that p is a prime above 3, that B is nonzero and A^2 is not 4 mod p, and that the
+// base point, if set, is on the curve. The arithmetic methods assume a valid curve and panic otherwise.
+func (c *MontgomeryCurve) Validate() error {
+	if c.P.Cmp(big.NewInt(3)) <= 0 || !c.P.ProbablyPrime(20) {
+		return errors.Wrap(ErrInvalidCurve, "p is not a prime above 3")
+	}
+	if new(big.Int).Mod(c.B, c.P).Sign() == 0 {
+		return errors.Wrap(ErrInvalidCurve, "B is zero")
+	}
+	a2 := new(big.Int).Mul(c.A, c.A)
+	if a2.Sub(a2, big.NewInt(4)).Mod(a2, c.P).Sign() == 0 {
+		return errors.Wrap(ErrInvalidCurve, "singular curve")
+	}
+	if c.U != nil && c.V != nil {
+		v2 := new(big.Int).Mul(c.V, c.V)
+		if v2.Mod(v2, c.P).Cmp(c.rhs(c.U)) != 0 {
+			return errors.Wrap(ErrInvalidPoint, "base point")
+		}
+	}
+	return nil
+}
+
+// inv returns a^-1 mod p. It panics if there is none, which happens only for a malformed curve.
+func (c *MontgomeryCurve) inv(a *big.Int) *big.Int {
+	x := new(big.Int)
+	if x.ModInverse(a, c.P) == nil {
+		panic("cryptopals: MontgomeryCurve: no inverse mod p; the curve is invalid")
+	}
+	return x
+}
+
+// OnTwist reports whether u is the coordinate of a point on the quadratic twist rather than on the curve.
+func (c *MontgomeryCurve) OnTwist(u *big.Int) bool {
+	return big.Jacobi(c.rhs(u), c.P) == -1
+}
+
+// LiftX returns a v with (u, v) on the curve, or false if u is on the twist.
+func (c *MontgomeryCurve) LiftX(u *big.Int) (*big.Int, bool) {
+	v := new(big.Int).ModSqrt(c.rhs(u), c.P)
+	return v, v != nil
+}
+
+// montgomeryDouble returns 2(X:Z) in projective x-only coordinates.
+func (c *MontgomeryCurve) montgomeryDouble(x, z *big.Int) (*big.Int, *big.Int) {
+	xx := new(big.Int).Mul(x, x)
+	zz := new(big.Int).Mul(z, z)
+	xz := new(big.Int).Mul(x, z)
+	x2 := new(big.Int).Sub(xx, zz)
+	x2.Mul(x2, x2).Mod(x2, c.P)
+	z2 := new(big.Int).Mul(c.A, xz)
+	z2.Add(z2, xx).Add(z2, zz).Mul(z2, xz).Lsh(z2, 2).Mod(z2, c.P)
+	return x2, z2
+}
+
+// montgomeryAdd returns P+Q in projective x-only coordinates, given P, Q and P-Q.
+func (c *MontgomeryCurve) montgomeryAdd(xp, zp, xq, zq, xd, zd *big.Int) (*big.Int, *big.Int) {
+	s := new(big.Int).Sub(xp, zp)
+	s.Mul(s, new(big.Int).Add(xq, zq))
+	t := new(big.Int).Add(xp, zp)
+	t.Mul(t, new(big.Int).Sub(xq, zq))
+	x := new(big.Int).Add(s, t)
+	x.Mul(x, x).Mul(x, zd).Mod(x, c.P)
+	z := s.Sub(s, t)
+	z.Mul(z, z).Mul(z, xd).Mod(z, c.P)
+	return x, z
+}
+
+// affineU returns X/Z, or 0 for the point at infinity.
+func (c *MontgomeryCurve) affineU(x, z *big.Int) *big.Int {
+	if new(big.Int).Mod(z, c.P).Sign() == 0 {
+		return new(big.Int)
+	}
+	u := c.inv(z)
+	u.Mul(u, x)
+	return u.Mod(u, c.P)
+}
+
+// Ladder returns the u coordinate of k times the point with coordinate u, using the Montgomery ladder.
+// The point at infinity is returned as 0.
+func (c *MontgomeryCurve) Ladder(u, k *big.Int) *big.Int {
+	x2, z2 := big.NewInt(1), new(big.Int)
+	x3, z3 := new(big.Int).Mod(u, c.P), big.NewInt(1)
+	for i := c.P.BitLen() - 1; i >= 0; i-- {
+		if k.Bit(i) == 1 {
+			x2, x3, z2, z3 = x3, x2, z3, z2
+		}
+		x3, z3 = c.montgomeryAdd(x2, z2, x3, z3, u, bigOne)
+		x2, z2 = c.montgomeryDouble(x2, z2)
+		if k.Bit(i) == 1 {
+			x2, x3, z2, z3 = x3, x2, z3, z2
+		}
+	}
+	return c.affineU(x2, z2)
+}
+
+// Weierstrass returns the equivalent short Weierstrass curve, with a = (3 - A^2)/3B^2 and
+// b = (2A^3 - 9A)/27B^3, and the image of the base point.
+func (c *MontgomeryCurve) Weierstrass() *WeierstrassCurve {
+	b2 := new(big.Int).Mul(c.B, c.B)
+	a := new(big.Int).Mul(c.A, c.A)
+	a.Sub(big.NewInt(3), a)
+	a.Mul(a, c.inv(b2.Mul(b2, big.NewInt(3)))).Mod(a, c.P)
+	b3 := new(big.Int).Exp(c.B, big.NewInt(3), c.P)
+	b := new(big.Int).Mul(c.A, c.A)
+	b.Lsh(b, 1).Sub(b, big.NewInt(9)).Mul(b, c.A)
+	b.Mul(b, c.inv(b3.Mul(b3, big.NewInt(27)))).Mod(b, c.P)
+	w := &WeierstrassCurve{A: a, B: b, P: c.P, N: c.N}
+	w.G = c.ToWeierstrass(c.U, c.V)
+	return w
+}
+
+// ToWeierstrass maps (u, v) to (u/B + A/3B, v/B) on the equivalent Weierstrass curve.
+func (c *MontgomeryCurve) ToWeierstrass(u, v *big.Int) *ECPoint {
+	binv := c.inv(c.B)
+	x := c.inv(big.NewInt(3))
+	x.Mul(x, c.A).Add(x, u).Mul(x, binv).Mod(x, c.P)
+	y := new(big.Int).Mul(v, binv)
+	return &ECPoint{X: x, Y: y.Mod(y, c.P)}
+}
+
+// FromWeierstrass maps a point on the equivalent Weierstrass curve back to (u, v).
+func (c *MontgomeryCurve) FromWeierstrass(p *ECPoint) (u, v *big.Int) {
+	u = c.inv(big.NewInt(3))
+	u.Mul(u, c.A).Neg(u)
+	u.Add(u, new(big.Int).Mul(p.X, c.B)).Mod(u, c.P)
+	v = new(big.Int).Mul(p.Y, c.B)
+	return u, v.Mod(v, c.P)
+}
@@ -0,0 +1,176 @@
+package cryptopals
+
+import (
+	"math/big"
+	"testing"
+)
+
+// smallCurve is y^2 = x^3 + 2x + 3 over GF(97), which has 100 points.
+var smallCurve = &WeierstrassCurve{A: big.NewInt(2), B: big.NewInt(3), P: big.NewInt(97)}
+
+func TestWeierstrassAdd(t *testing.T) {
+	c := smallCurve
+	tests := []struct {
+		p1, p2, want *ECPoint
+	}{
+		{p1: NewECPoint(3, 6), p2: NewECPoint(3, 6), want: NewECPoint(80, 10)},
+		{p1: NewECPoint(3, 6), p2: NewECPoint(80, 10), want: NewECPoint(80, 87)},
+		{p1: NewECPoint(3, 6), p2: NewECPoint(3, 91), want: c.Identity()},
+		{p1: c.Identity(), p2: NewECPoint(3, 6), want: NewECPoint(3, 6)},
+		{p1: NewECPoint(3, 6), p2: c.Identity(), want: NewECPoint(3, 6)},
+		{p1: NewECPoint(3, 6), p2: NewECPoint(3+97, 6), want: NewECPoint(80, 10)},
+		{p1: NewECPoint(3, 6), p2: NewECPoint(3+97, 91), want: c.Identity()},
+	}
+	for _, tt := range tests {
+		if got := c.Add(tt.p1, tt.p2); !got.Equal(tt.want) {
+			t.Errorf("Add(%v, %v) = %v, want %v", tt.p1, tt.p2, got, tt.want)
+		}
+	}
+}
+
+func TestWeierstrassValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		c    *WeierstrassCurve
+		ok   bool
+	}{
+		{"challenge 59", Challenge59Curve, true},
+		{"small", smallCurve, true},
+		{"composite p", &WeierstrassCurve{A: big.NewInt(2), B: big.NewInt(3), P: big.NewInt(91)}, false},
+		{"singular", &WeierstrassCurve{A: big.NewInt(-3), B: big.NewInt(2), P: big.NewInt(97)}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.c.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: Validate() error = %v", tt.name, err)
+		}
+	}
+
+	// Over Z/91Z, x2 - x1 = 7 has no inverse.
+	defer func() {
+		if recover() == nil {
+			t.Error("Add() over a composite modulus did not panic")
+		}
+	}()
+	c := &WeierstrassCurve{A: big.NewInt(2), B: big.NewInt(3), P: big.NewInt(91)}
+	c.Add(NewECPoint(1, 2), NewECPoint(8, 5))
+}
+
+func TestWeierstrassScalarMult(t *testing.T) {
+	c := smallCurve
+	p := NewECPoint(3, 6)
+	q := c.Identity()
+	for k := int64(0); k < 10; k++ {
+		if got := c.ScalarMult(p, big.NewInt(k)); !got.Equal(q) {
+			t.Errorf("ScalarMult(p, %d) = %v, want %v", k, got, q)
+		}
+		if !c.IsOnCurve(q) {
+			t.Errorf("%d*p = %v is not on the curve", k, q)
+		}
+		q = c.Add(q, p)
+	}
+	if got := c.ScalarMult(p, big.NewInt(5)); !got.IsIdentity() {
+		t.Errorf("ScalarMult(p, 5) = %v, want identity", got)
+	}
+	if got, want := c.ScalarMult(p, big.NewInt(-2)), c.Neg(c.Double(p)); !got.Equal(want) {
+		t.Errorf("ScalarMult(p, -2) = %v, want %v", got, want)
+	}
+}
+
+func TestChallenge59Curve(t *testing.T) {
+	c := Challenge59Curve
+	if !c.IsOnCurve(c.G) {
+		t.Fatal("base point not on curve")
+	}
+	if !c.ScalarMult(c.G, c.N).IsIdentity() {
+		t.Error("base point does not have order N")
+	}
+	for _, ic := range Challenge59InvalidCurves {
+		bad := c.WithB(ic.B)
+		p, err := bad.RandomPoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.IsOnCurve(p) {
+			t.Errorf("point %v on curve b=%v is also on the target curve", p, ic.B)
+		}
+		if !bad.ScalarMult(p, ic.Order).IsIdentity() {
+			t.Errorf("curve b=%v does not have order %v", ic.B, ic.Order)
+		}
+	}
+}
+
+func TestECDH(t *testing.T) {
+	c := Challenge59Curve
+	a, A, err := c.GenerateECKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, B, err := c.GenerateECKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s1, s2 := c.ScalarMult(B, a), c.ScalarMult(A, b); !s1.Equal(s2) {
+		t.Errorf("shared secrets differ: %v != %v", s1, s2)
+	}
+}
+
+func TestMontgomeryWeierstrass(t *testing.T) {
+	c := Challenge60Curve
+	w := c.Weierstrass()
+	want := Challenge59Curve
+	if w.A.Cmp(new(big.Int).Mod(want.A, want.P)) != 0 || w.B.Cmp(want.B) != 0 {
+		t.Errorf("Weierstrass() = (a=%v, b=%v), want (a=%v, b=%v)", w.A, w.B, want.A, want.B)
+	}
+	if !w.G.Equal(want.G) {
+		t.Errorf("Weierstrass().G = %v, want %v", w.G, want.G)
+	}
+	if u, v := c.FromWeierstrass(w.G); u.Cmp(c.U) != 0 || v.Cmp(c.V) != 0 {
+		t.Errorf("FromWeierstrass(G) = (%v, %v), want (%v, %v)", u, v, c.U, c.V)
+	}
+}
+
+func TestMontgomeryLadder(t *testing.T) {
+	c := Challenge60Curve
+	w := c.Weierstrass()
+	for _, k := range []int64{0, 1, 2, 3, 7, 1 << 20, 123456789} {
+		got := c.Ladder(c.U, big.NewInt(k))
+		want := new(big.Int)
+		if p := w.ScalarMult(w.G, big.NewInt(k)); !p.IsIdentity() {
+			want, _ = c.FromWeierstrass(p)
+		}
+		if got.Cmp(want) != 0 {
+			t.Errorf("Ladder(U, %d) = %v, want %v", k, got, want)
+		}
+	}
+	if got := c.Ladder(c.U, c.N); got.Sign() != 0 {
+		t.Errorf("Ladder(U, N) = %v, want 0", got)
+	}
+	if c.OnTwist(c.U) {
+		t.Error("base point reported as on the twist")
+	}
+	if _, ok := c.LiftX(c.U); !ok {
+		t.Error("LiftX(U) failed")
+	}
+	// (0, 0) has order 2, also when given as u = p.
+	if got := c.Ladder(c.P, big.NewInt(2)); got.Sign() != 0 {
+		t.Errorf("Ladder(p, 2) = %v, want 0", got)
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+
+	// A malformed curve over Z/91Z is rejected by Validate, and B = 7 has no inverse, so converting it
+	// panics rather than returning garbage.
+	bad := *c
+	bad.P = big.NewInt(91)
+	bad.B = big.NewInt(7)
+	if err := bad.Validate(); err == nil {
+		t.Error("Validate() over Z/91Z succeeded")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Weierstrass() over Z/91Z did not panic")
+		}
+	}()
+	bad.Weierstrass()
+}
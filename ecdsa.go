@@ -0,0 +1,111 @@
+package cryptopals
+
+import (
+	"crypto/elliptic"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// P256 is NIST P-256 as a WeierstrassCurve.
+var P256 = func() *WeierstrassCurve {
+	params := elliptic.P256().Params()
+	return &WeierstrassCurve{
+		A: big.NewInt(-3),
+		B: params.B,
+		P: params.P,
+		G: &ECPoint{X: params.Gx, Y: params.Gy},
+		N: params.N,
+	}
+}()
+
+// ECDSAPublicKey is an ECDSA public key Q = dG on Curve.
+type ECDSAPublicKey struct {
+	Curve *WeierstrassCurve
+	Q     *ECPoint
+}
+
+// ECDSAPrivateKey is an ECDSA private key.
+type ECDSAPrivateKey struct {
+	ECDSAPublicKey
+	D *big.Int
+}
+
+// GenerateECDSAKey returns a random key on curve.
+func GenerateECDSAKey(curve *WeierstrassCurve) (*ECDSAPrivateKey, error) {
+	d, q, err := curve.GenerateECKey()
+	if err != nil {
+		return nil, err
+	}
+	return &ECDSAPrivateKey{ECDSAPublicKey: ECDSAPublicKey{Curve: curve, Q: q}, D: d}, nil
+}
+
+// Sign signs hashed with a random nonce.
+func (k *ECDSAPrivateKey) Sign(hashed []byte) (r, s *big.Int, err error) {
+	for {
+		nonce, err := randRange(bigOne, k.Curve.N)
+		if err != nil {
+			return nil, nil, err
+		}
+		r, s, err = k.SignWithNonce(hashed, nonce)
+		if err == nil {
+			return r, s, nil
+		}
+	}
+}
+
+// SignWithNonce signs hashed using the caller-provided nonce. Reusing or leaking bits of the nonce reveals the private key.
+func (k *ECDSAPrivateKey) SignWithNonce(hashed []byte, nonce *big.Int) (r, s *big.Int, err error) {
+	n := k.Curve.N
+	kinv, err := InvMod(nonce, n)
+	if err != nil {
+		return nil, nil, err
+	}
+	// r = (kG).x mod n
+	p := k.Curve.ScalarMult(k.Curve.G, nonce)
+	if p.IsIdentity() {
+		return nil, nil, errors.New("nonce is a multiple of the group order")
+	}
+	r = new(big.Int).Mod(p.X, n)
+	if r.Sign() == 0 {
+		return nil, nil, errors.New("r is zero")
+	}
+	// s = k^-1 (H(m) + dr) mod n
+	s = new(big.Int).Mul(k.D, r)
+	s.Add(s, hashToInt(hashed, n))
+	s.Mul(s, kinv).Mod(s, n)
+	if s.Sign() == 0 {
+		return nil, nil, errors.New("s is zero")
+	}
+	return r, s, nil
+}
+
+// VerifyECDSA reports whether (r, s) is a valid signature of hashed under pub.
+func VerifyECDSA(pub *ECDSAPublicKey, hashed []byte, r, s *big.Int) bool {
+	n := pub.Curve.N
+	if r.Sign() <= 0 || r.Cmp(n) >= 0 || s.Sign() <= 0 || s.Cmp(n) >= 0 {
+		return false
+	}
+	u1, u2, err := ecdsaScalars(pub, hashed, r, s)
+	if err != nil {
+		return false
+	}
+	// R = u1 G + u2 Q
+	p := pub.Curve.Add(pub.Curve.ScalarMult(pub.Curve.G, u1), pub.Curve.ScalarMult(pub.Q, u2))
+	if p.IsIdentity() {
+		return false
+	}
+	return new(big.Int).Mod(p.X, n).Cmp(r) == 0
+}
+
+// ecdsaScalars returns u1 = H(m) s^-1 and u2 = r s^-1 mod n.
+func ecdsaScalars(pub *ECDSAPublicKey, hashed []byte, r, s *big.Int) (u1, u2 *big.Int, err error) {
+	n := pub.Curve.N
+	w, err := InvMod(s, n)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "s")
+	}
+	u1 = new(big.Int).Mul(hashToInt(hashed, n), w)
+	u2 = new(big.Int).Mul(r, w)
+	return u1.Mod(u1, n), u2.Mod(u2, n), nil
+}
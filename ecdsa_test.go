@@ -0,0 +1,50 @@
+package cryptopals
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+)
+
+func TestECDSA(t *testing.T) {
+	k, err := GenerateECDSAKey(Challenge59Curve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed := sha256.Sum256([]byte("hi mom"))
+	r, s, err := k.Sign(hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyECDSA(&k.ECDSAPublicKey, hashed[:], r, s) {
+		t.Error("VerifyECDSA() rejected a valid signature")
+	}
+	other := sha256.Sum256([]byte("hi dad"))
+	if VerifyECDSA(&k.ECDSAPublicKey, other[:], r, s) {
+		t.Error("VerifyECDSA() accepted a signature over another message")
+	}
+	if VerifyECDSA(&k.ECDSAPublicKey, hashed[:], r, new(big.Int).Add(s, bigOne)) {
+		t.Error("VerifyECDSA() accepted a modified signature")
+	}
+	if VerifyECDSA(&k.ECDSAPublicKey, hashed[:], r, new(big.Int)) {
+		t.Error("VerifyECDSA() accepted s = 0")
+	}
+}
+
+func TestECDSAP256Stdlib(t *testing.T) {
+	k, err := GenerateECDSAKey(P256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed := sha256.Sum256([]byte("hi mom"))
+	r, s, err := k.Sign(hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: k.Q.X, Y: k.Q.Y}
+	if !ecdsa.Verify(pub, hashed[:], r, s) {
+		t.Error("crypto/ecdsa rejected our P-256 signature")
+	}
+}
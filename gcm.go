@@ -0,0 +1,491 @@
+package cryptopals
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// ErrAuthentication is returned when a GCM tag does not verify.
+var ErrAuthentication = errors.New("message authentication failed")
+
+// GF128 is an element of GF(2^128) = GF(2)[x]/(x^128 + x^7 + x^2 + x + 1) in GCM's bit order: the most
+// significant bit of Hi, the first bit of the block, is the coefficient of x^0.
+type GF128 struct {
+	Hi, Lo uint64
+}
+
+// GF128FromBytes returns the element encoded by the 16-byte block b.
+func GF128FromBytes(b []byte) GF128 {
+	return GF128{Hi: binary.BigEndian.Uint64(b[:8]), Lo: binary.BigEndian.Uint64(b[8:16])}
+}
+
+// Bytes returns the 16-byte block encoding a.
+func (a GF128) Bytes() []byte {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b[:8], a.Hi)
+	binary.BigEndian.PutUint64(b[8:], a.Lo)
+	return b
+}
+
+// gf128One is the multiplicative identity.
+var gf128One = GF128{Hi: 1 << 63}
+
+// IsZero reports whether a is zero.
+func (a GF128) IsZero() bool {
+	return a.Hi == 0 && a.Lo == 0
+}
+
+// Add returns a + b, which is also a - b.
+func (a GF128) Add(b GF128) GF128 {
+	return GF128{Hi: a.Hi ^ b.Hi, Lo: a.Lo ^ b.Lo}
+}
+
+// Mul returns a * b, using the shift-and-add algorithm of NIST SP 800-38D.
+func (a GF128) Mul(b GF128) GF128 {
+	var z GF128
+	v := b
+	for i := 0; i < 128; i++ {
+		var bit uint64
+		if i < 64 {
+			bit = a.Hi >> (63 - i) & 1
+		} else {
+			bit = a.Lo >> (127 - i) & 1
+		}
+		if bit == 1 {
+			z = z.Add(v)
+		}
+		// Multiply v by x: a right shift in this bit order, reducing by x^128 = x^7 + x^2 + x + 1.
+		carry := v.Lo & 1
+		v.Lo = v.Lo>>1 | v.Hi<<63
+		v.Hi >>= 1
+		if carry == 1 {
+			v.Hi ^= 0xe1 << 56
+		}
+	}
+	return z
+}
+
+// Exp returns a^e.
+func (a GF128) Exp(e *big.Int) GF128 {
+	z := gf128One
+	for i := e.BitLen() - 1; i >= 0; i-- {
+		z = z.Mul(z)
+		if e.Bit(i) == 1 {
+			z = z.Mul(a)
+		}
+	}
+	return z
+}
+
+// gf128Order is 2^128 - 1, the order of the multiplicative group.
+var gf128Order = new(big.Int).Sub(new(big.Int).Lsh(bigOne, 128), bigOne)
+
+// Inverse returns a^-1 = a^(2^128 - 2). The inverse of zero is zero.
+func (a GF128) Inverse() GF128 {
+	return a.Exp(new(big.Int).Sub(gf128Order, bigOne))
+}
+
+// Sqrt returns the square root a^(2^127), which is unique in characteristic 2.
+func (a GF128) Sqrt() GF128 {
+	for i := 0; i < 127; i++ {
+		a = a.Mul(a)
+	}
+	return a
+}
+
+// GHASH returns GHASH_H(ad, ciphertext) as specified for GCM: the zero-padded blocks of ad and
+// ciphertext, then a block of their bit lengths, are absorbed as y = (y + b) * H.
+func GHASH(h GF128, ad, ciphertext []byte) GF128 {
+	var y GF128
+	for _, b := range ghashBlocks(ad, ciphertext) {
+		y = y.Add(b).Mul(h)
+	}
+	return y
+}
+
+// ghashBlocks returns the blocks GHASH absorbs for ad and ciphertext.
+func ghashBlocks(ad, ciphertext []byte) []GF128 {
+	var blocks []GF128
+	for _, data := range [][]byte{ad, ciphertext} {
+		for i := 0; i < len(data); i += 16 {
+			block := make([]byte, 16)
+			copy(block, data[i:])
+			blocks = append(blocks, GF128FromBytes(block))
+		}
+	}
+	return append(blocks, GF128{Hi: uint64(len(ad)) * 8, Lo: uint64(len(ciphertext)) * 8})
+}
+
+// GCM is AES-GCM (or GCM over any 128-bit block cipher) with 96-bit nonces and 128-bit tags.
+type GCM struct {
+	block cipher.Block
+	h     GF128
+}
+
+// GCMNonceSize and GCMTagSize are the nonce and tag sizes used by GCM.
+const (
+	GCMNonceSize = 12
+	GCMTagSize   = 16
+)
+
+// NewGCM returns GCM mode for block, with authentication key H = E(0).
+func NewGCM(block cipher.Block) (*GCM, error) {
+	if block.BlockSize() != 16 {
+		return nil, errors.New("GCM requires a 128-bit block cipher")
+	}
+	h := make([]byte, 16)
+	block.Encrypt(h, h)
+	return &GCM{block: block, h: GF128FromBytes(h)}, nil
+}
+
+// H returns the GHASH key.
+func (g *GCM) H() GF128 {
+	return g.h
+}
+
+// counterBlock returns nonce || ctr.
+func counterBlock(nonce []byte, ctr uint32) []byte {
+	b := make([]byte, 16)
+	copy(b, nonce)
+	binary.BigEndian.PutUint32(b[12:], ctr)
+	return b
+}
+
+// ctr XORs src with the keystream starting at counter 2, which follows J0 = nonce || 1.
+func (g *GCM) ctr(nonce, src []byte) []byte {
+	dst := make([]byte, len(src))
+	ks := make([]byte, 16)
+	for i, ctr := 0, uint32(2); i < len(src); i, ctr = i+16, ctr+1 {
+		g.block.Encrypt(ks, counterBlock(nonce, ctr))
+		for j := i; j < i+16 && j < len(src); j++ {
+			dst[j] = src[j] ^ ks[j-i]
+		}
+	}
+	return dst
+}
+
+// tag returns GHASH(ad, ciphertext) + E(J0).
+func (g *GCM) tag(nonce, ad, ciphertext []byte) []byte {
+	s := make([]byte, 16)
+	g.block.Encrypt(s, counterBlock(nonce, 1))
+	return GHASH(g.h, ad, ciphertext).Add(GF128FromBytes(s)).Bytes()
+}
+
+// Seal encrypts and authenticates plaintext and authenticates ad, returning ciphertext || tag.
+func (g *GCM) Seal(nonce, plaintext, ad []byte) ([]byte, error) {
+	if len(nonce) != GCMNonceSize {
+		return nil, ErrMismatchedLength
+	}
+	c := g.ctr(nonce, plaintext)
+	return append(c, g.tag(nonce, ad, c)...), nil
+}
+
+// Open authenticates ciphertext || tag and ad, and decrypts the ciphertext.
+func (g *GCM) Open(nonce, sealed, ad []byte) ([]byte, error) {
+	if len(nonce) != GCMNonceSize {
+		return nil, ErrMismatchedLength
+	}
+	if len(sealed) < GCMTagSize {
+		return nil, ErrAuthentication
+	}
+	c, t := sealed[:len(sealed)-GCMTagSize], sealed[len(sealed)-GCMTagSize:]
+	if subtle.ConstantTimeCompare(g.tag(nonce, ad, c), t) != 1 {
+		return nil, ErrAuthentication
+	}
+	return g.ctr(nonce, c), nil
+}
+
+// GFPoly is a polynomial over GF(2^128), with coefficients from the constant term up. The zero
+// polynomial is empty, and results never have a zero leading coefficient.
+type GFPoly []GF128
+
+// norm trims zero leading coefficients.
+func (p GFPoly) norm() GFPoly {
+	for len(p) > 0 && p[len(p)-1].IsZero() {
+		p = p[:len(p)-1]
+	}
+	return p
+}
+
+// Degree returns the degree of p, or -1 for the zero polynomial.
+func (p GFPoly) Degree() int {
+	return len(p.norm()) - 1
+}
+
+// Equal reports whether p and q are the same polynomial.
+func (p GFPoly) Equal(q GFPoly) bool {
+	p, q = p.norm(), q.norm()
+	if len(p) != len(q) {
+		return false
+	}
+	for i := range p {
+		if p[i] != q[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// IsOne reports whether p is the constant 1.
+func (p GFPoly) IsOne() bool {
+	return p.Equal(GFPoly{gf128One})
+}
+
+// Eval returns p(x).
+func (p GFPoly) Eval(x GF128) GF128 {
+	var y GF128
+	for i := len(p) - 1; i >= 0; i-- {
+		y = y.Mul(x).Add(p[i])
+	}
+	return y
+}
+
+// Add returns p + q.
+func (p GFPoly) Add(q GFPoly) GFPoly {
+	if len(p) < len(q) {
+		p, q = q, p
+	}
+	r := append(GFPoly{}, p...)
+	for i := range q {
+		r[i] = r[i].Add(q[i])
+	}
+	return r.norm()
+}
+
+// Mul returns p * q.
+func (p GFPoly) Mul(q GFPoly) GFPoly {
+	p, q = p.norm(), q.norm()
+	if len(p) == 0 || len(q) == 0 {
+		return nil
+	}
+	r := make(GFPoly, len(p)+len(q)-1)
+	for i := range p {
+		for j := range q {
+			r[i+j] = r[i+j].Add(p[i].Mul(q[j]))
+		}
+	}
+	return r.norm()
+}
+
+// DivMod returns the quotient and remainder of p divided by the nonzero polynomial q.
+func (p GFPoly) DivMod(q GFPoly) (quo, rem GFPoly) {
+	q = q.norm()
+	if len(q) == 0 {
+		panic("GFPoly: division by zero")
+	}
+	rem = append(GFPoly{}, p.norm()...)
+	if len(rem) < len(q) {
+		return nil, rem
+	}
+	inv := q[len(q)-1].Inverse()
+	quo = make(GFPoly, len(rem)-len(q)+1)
+	for i := len(rem) - len(q); i >= 0; i-- {
+		c := rem[i+len(q)-1].Mul(inv)
+		quo[i] = c
+		for j := range q {
+			rem[i+j] = rem[i+j].Add(c.Mul(q[j]))
+		}
+	}
+	return quo.norm(), rem.norm()
+}
+
+// Mod returns p mod q.
+func (p GFPoly) Mod(q GFPoly) GFPoly {
+	_, r := p.DivMod(q)
+	return r
+}
+
+// Monic returns p divided by its leading coefficient.
+func (p GFPoly) Monic() GFPoly {
+	p = p.norm()
+	if len(p) == 0 {
+		return nil
+	}
+	inv := p[len(p)-1].Inverse()
+	r := make(GFPoly, len(p))
+	for i := range p {
+		r[i] = p[i].Mul(inv)
+	}
+	return r
+}
+
+// GCD returns the monic greatest common divisor of p and q.
+func (p GFPoly) GCD(q GFPoly) GFPoly {
+	a, b := p.norm(), q.norm()
+	for len(b) > 0 {
+		a, b = b, a.Mod(b)
+	}
+	return a.Monic()
+}
+
+// PowMod returns p^e mod m.
+func (p GFPoly) PowMod(e *big.Int, m GFPoly) GFPoly {
+	r := GFPoly{gf128One}.Mod(m)
+	base := p.Mod(m)
+	for i := e.BitLen() - 1; i >= 0; i-- {
+		r = r.Mul(r).Mod(m)
+		if e.Bit(i) == 1 {
+			r = r.Mul(base).Mod(m)
+		}
+	}
+	return r
+}
+
+// Derivative returns the formal derivative of p. In characteristic 2 the even-degree terms vanish.
+func (p GFPoly) Derivative() GFPoly {
+	if len(p) < 2 {
+		return nil
+	}
+	d := make(GFPoly, len(p)-1)
+	for i := 1; i < len(p); i += 2 {
+		d[i-1] = p[i]
+	}
+	return d.norm()
+}
+
+// sqrt returns the polynomial whose square is p, which must have only even-degree terms.
+func (p GFPoly) sqrt() GFPoly {
+	r := make(GFPoly, (len(p)+1)/2)
+	for i := range r {
+		r[i] = p[2*i].Sqrt()
+	}
+	return r.norm()
+}
+
+// GFPolyFactor is a factor of a polynomial and its multiplicity or degree, depending on the factorization.
+type GFPolyFactor struct {
+	Poly GFPoly
+	N    int
+}
+
+// SquareFreeFactorization returns square-free, pairwise coprime monic polynomials f_i and multiplicities
+// with p = lc(p) * prod f_i^N_i, using Yun's algorithm adapted to characteristic 2.
+func (p GFPoly) SquareFreeFactorization() []GFPolyFactor {
+	p = p.Monic()
+	if p.Degree() < 1 {
+		return nil
+	}
+	var factors []GFPolyFactor
+	d := p.Derivative()
+	if d.Degree() < 0 {
+		// p is a square.
+		for _, f := range p.sqrt().SquareFreeFactorization() {
+			factors = append(factors, GFPolyFactor{Poly: f.Poly, N: 2 * f.N})
+		}
+		return factors
+	}
+	c := p.GCD(d)
+	w, _ := p.DivMod(c)
+	for i := 1; !w.IsOne(); i++ {
+		y := w.GCD(c)
+		fac, _ := w.DivMod(y)
+		if !fac.IsOne() {
+			factors = append(factors, GFPolyFactor{Poly: fac.Monic(), N: i})
+		}
+		w = y
+		c, _ = c.DivMod(y)
+	}
+	if !c.IsOne() {
+		for _, f := range c.Monic().sqrt().SquareFreeFactorization() {
+			factors = append(factors, GFPolyFactor{Poly: f.Poly, N: 2 * f.N})
+		}
+	}
+	return factors
+}
+
+// xPoly is the polynomial x.
+var xPoly = GFPoly{{}, gf128One}
+
+// frobenius returns p^(2^128) mod m.
+func frobenius(p, m GFPoly) GFPoly {
+	for i := 0; i < 128; i++ {
+		p = p.Mul(p).Mod(m)
+	}
+	return p
+}
+
+// DistinctDegreeFactorization splits the monic square-free polynomial p into products of all its
+// irreducible factors of each degree, using gcd(p, x^(q^i) - x) with q = 2^128.
+func (p GFPoly) DistinctDegreeFactorization() []GFPolyFactor {
+	var factors []GFPolyFactor
+	f := p.Monic()
+	h := xPoly.Mod(f)
+	for i := 1; f.Degree() >= 2*i; i++ {
+		h = frobenius(h, f)
+		g := f.GCD(h.Add(xPoly))
+		if !g.IsOne() {
+			factors = append(factors, GFPolyFactor{Poly: g, N: i})
+			f, _ = f.DivMod(g)
+			h = h.Mod(f)
+		}
+	}
+	if f.Degree() > 0 {
+		factors = append(factors, GFPolyFactor{Poly: f, N: f.Degree()})
+	}
+	return factors
+}
+
+// EqualDegreeFactorization splits the monic square-free polynomial p, all of whose irreducible factors
+// have degree d, into those factors with the Cantor-Zassenhaus algorithm. In characteristic 2 the
+// random splitting polynomial is the trace a + a^2 + ... + a^(2^(128d-1)) mod p of a random a.
+func (p GFPoly) EqualDegreeFactorization(d int) ([]GFPoly, error) {
+	p = p.Monic()
+	n := p.Degree()
+	if n%d != 0 {
+		return nil, errors.Errorf("degree %d is not a multiple of %d", n, d)
+	}
+	factors := []GFPoly{p}
+	for len(factors) < n/d {
+		a := make(GFPoly, n)
+		for i := range a {
+			a[i] = GF128FromBytes(RandomNBytes(16))
+		}
+		t := a.Mod(p)
+		sq := t
+		for i := 1; i < 128*d; i++ {
+			sq = sq.Mul(sq).Mod(p)
+			t = t.Add(sq)
+		}
+		var next []GFPoly
+		for _, f := range factors {
+			if f.Degree() == d {
+				next = append(next, f)
+				continue
+			}
+			g := f.GCD(t.Mod(f))
+			if g.Degree() < 1 || g.Degree() == f.Degree() {
+				next = append(next, f)
+				continue
+			}
+			q, _ := f.DivMod(g)
+			next = append(next, g, q.Monic())
+		}
+		factors = next
+	}
+	return factors, nil
+}
+
+// Roots returns the distinct roots of p in GF(2^128).
+func (p GFPoly) Roots() ([]GF128, error) {
+	var roots []GF128
+	for _, sf := range p.SquareFreeFactorization() {
+		for _, dd := range sf.Poly.DistinctDegreeFactorization() {
+			if dd.N != 1 {
+				continue
+			}
+			linear, err := dd.Poly.EqualDegreeFactorization(1)
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range linear {
+				// f = x + c has root c.
+				roots = append(roots, f[0])
+			}
+		}
+	}
+	return roots, nil
+}
@@ -0,0 +1,177 @@
+package cryptopals
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"math/big"
+	"sort"
+	"testing"
+)
+
+func TestGF128(t *testing.T) {
+	a := GF128FromBytes(RandomNBytes(16))
+	b := GF128FromBytes(RandomNBytes(16))
+	c := GF128FromBytes(RandomNBytes(16))
+	if got := a.Mul(gf128One); got != a {
+		t.Errorf("a * 1 = %v, want %v", got, a)
+	}
+	if got, want := a.Mul(b.Add(c)), a.Mul(b).Add(a.Mul(c)); got != want {
+		t.Errorf("a(b + c) = %v, want %v", got, want)
+	}
+	if got, want := a.Mul(b), b.Mul(a); got != want {
+		t.Errorf("ab = %v, ba = %v", got, want)
+	}
+	if got := a.Mul(a.Inverse()); got != gf128One {
+		t.Errorf("a * a^-1 = %v, want 1", got)
+	}
+	if got := a.Sqrt().Mul(a.Sqrt()); got != a {
+		t.Errorf("sqrt(a)^2 = %v, want %v", got, a)
+	}
+	if got := a.Exp(gf128Order); got != gf128One {
+		t.Errorf("a^(2^128-1) = %v, want 1", got)
+	}
+	// x * x^127 = x^128 = x^7 + x^2 + x + 1.
+	x := GF128{Hi: 1 << 62}
+	x127 := GF128{Lo: 1}
+	if got, want := x.Mul(x127), (GF128{Hi: 0xe1 << 56}); got != want {
+		t.Errorf("x * x^127 = %v, want %v", got, want)
+	}
+	if got := GF128FromBytes(a.Bytes()); got != a {
+		t.Errorf("GF128FromBytes(a.Bytes()) = %v, want %v", got, a)
+	}
+}
+
+func TestGCMStdlib(t *testing.T) {
+	block, err := aes.NewCipher(RandomNBytes(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ours, err := NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	std, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []struct{ ad, pt int }{{0, 0}, {0, 16}, {13, 0}, {20, 37}, {16, 64}, {5, 100}} {
+		nonce, ad, pt := RandomNBytes(GCMNonceSize), RandomNBytes(n.ad), RandomNBytes(n.pt)
+		got, err := ours.Seal(nonce, pt, ad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := std.Seal(nil, nonce, pt, ad); !bytes.Equal(got, want) {
+			t.Errorf("Seal(ad %d, pt %d) = %x, want %x", n.ad, n.pt, got, want)
+		}
+		opened, err := ours.Open(nonce, got, ad)
+		if err != nil || !bytes.Equal(opened, pt) {
+			t.Errorf("Open(ad %d, pt %d) = %x, %v, want %x", n.ad, n.pt, opened, err, pt)
+		}
+		got[0] ^= 1
+		if _, err := ours.Open(nonce, got, ad); err != ErrAuthentication {
+			t.Errorf("Open() of tampered message: error = %v, want %v", err, ErrAuthentication)
+		}
+	}
+}
+
+// polyFromRoots returns (x + r_1)...(x + r_n) for the given roots.
+func polyFromRoots(roots ...GF128) GFPoly {
+	p := GFPoly{gf128One}
+	for _, r := range roots {
+		p = p.Mul(GFPoly{r, gf128One})
+	}
+	return p
+}
+
+func sortedRoots(roots []GF128) []GF128 {
+	sort.Slice(roots, func(i, j int) bool {
+		if roots[i].Hi != roots[j].Hi {
+			return roots[i].Hi < roots[j].Hi
+		}
+		return roots[i].Lo < roots[j].Lo
+	})
+	return roots
+}
+
+func TestGFPolyDivMod(t *testing.T) {
+	p := GFPoly{GF128FromBytes(RandomNBytes(16)), GF128FromBytes(RandomNBytes(16)), GF128FromBytes(RandomNBytes(16)), gf128One}
+	q := GFPoly{GF128FromBytes(RandomNBytes(16)), GF128FromBytes(RandomNBytes(16))}
+	quo, rem := p.DivMod(q)
+	if rem.Degree() >= q.Degree() {
+		t.Errorf("remainder degree %d, want < %d", rem.Degree(), q.Degree())
+	}
+	if got := quo.Mul(q).Add(rem); !got.Equal(p) {
+		t.Errorf("quo*q + rem = %v, want %v", got, p)
+	}
+	if got := p.PowMod(big.NewInt(3), q); !got.Equal(p.Mul(p).Mul(p).Mod(q)) {
+		t.Errorf("PowMod(p, 3) = %v", got)
+	}
+	if got := p.Mul(q).GCD(q.Mul(GFPoly{gf128One, gf128One})); !got.Equal(q.Monic()) {
+		t.Errorf("GCD() = %v, want %v", got, q.Monic())
+	}
+}
+
+func TestGFPolyFactorization(t *testing.T) {
+	r1, r2, r3 := GF128FromBytes(RandomNBytes(16)), GF128FromBytes(RandomNBytes(16)), GF128FromBytes(RandomNBytes(16))
+
+	// (x + r1)^3 (x + r2)^2 (x + r3)
+	p := polyFromRoots(r1, r1, r1, r2, r2, r3)
+	sff := p.SquareFreeFactorization()
+	want := map[int]GFPoly{1: polyFromRoots(r3), 2: polyFromRoots(r2), 3: polyFromRoots(r1)}
+	if len(sff) != len(want) {
+		t.Fatalf("SquareFreeFactorization() returned %d factors, want %d", len(sff), len(want))
+	}
+	for _, f := range sff {
+		if !f.Poly.Equal(want[f.N]) {
+			t.Errorf("square-free factor of multiplicity %d = %v, want %v", f.N, f.Poly, want[f.N])
+		}
+	}
+
+	// An irreducible quadratic times two linear factors: x^2 + x + c is irreducible when c has trace 1.
+	var quad GFPoly
+	for {
+		c := GF128FromBytes(RandomNBytes(16))
+		quad = GFPoly{c, gf128One, gf128One}
+		if ddf := quad.DistinctDegreeFactorization(); len(ddf) == 1 && ddf[0].N == 2 {
+			break
+		}
+	}
+	q := quad.Mul(polyFromRoots(r1, r2))
+	ddf := q.DistinctDegreeFactorization()
+	if len(ddf) != 2 || ddf[0].N != 1 || !ddf[0].Poly.Equal(polyFromRoots(r1, r2)) || ddf[1].N != 2 || !ddf[1].Poly.Equal(quad) {
+		t.Errorf("DistinctDegreeFactorization() = %v", ddf)
+	}
+
+	edf, err := polyFromRoots(r1, r2, r3).EqualDegreeFactorization(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []GF128
+	for _, f := range edf {
+		got = append(got, f[0])
+	}
+	if want := sortedRoots([]GF128{r1, r2, r3}); !equalGF128s(sortedRoots(got), want) {
+		t.Errorf("EqualDegreeFactorization() roots = %v, want %v", got, want)
+	}
+
+	roots, err := p.Mul(quad).Roots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := sortedRoots([]GF128{r1, r2, r3}); !equalGF128s(sortedRoots(roots), want) {
+		t.Errorf("Roots() = %v, want %v", roots, want)
+	}
+}
+
+func equalGF128s(a, b []GF128) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
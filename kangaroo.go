@@ -0,0 +1,158 @@
+package cryptopals
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// GroupElement is an element of a Group.
+type GroupElement interface{}
+
+// Group is a cyclic group, written multiplicatively.
+type Group interface {
+	// Mul returns a*b.
+	Mul(a, b GroupElement) GroupElement
+	// Exp returns a^k.
+	Exp(a GroupElement, k *big.Int) GroupElement
+	// Equal reports whether a and b are the same element.
+	Equal(a, b GroupElement) bool
+	// Hash maps an element to an integer, used to pick pseudo-random jumps.
+	Hash(a GroupElement) uint64
+}
+
+// ModPGroup is the multiplicative group of integers modulo P. Its elements are *big.Int.
+type ModPGroup struct {
+	P *big.Int
+}
+
+// Mul returns a*b mod p.
+func (g ModPGroup) Mul(a, b GroupElement) GroupElement {
+	z := new(big.Int).Mul(a.(*big.Int), b.(*big.Int))
+	return z.Mod(z, g.P)
+}
+
+// Exp returns a^k mod p.
+func (g ModPGroup) Exp(a GroupElement, k *big.Int) GroupElement {
+	return new(big.Int).Exp(a.(*big.Int), k, g.P)
+}
+
+// Equal reports whether a and b are equal.
+func (g ModPGroup) Equal(a, b GroupElement) bool {
+	return a.(*big.Int).Cmp(b.(*big.Int)) == 0
+}
+
+// Hash returns the low 64 bits of a.
+func (g ModPGroup) Hash(a GroupElement) uint64 {
+	words := a.(*big.Int).Bits()
+	if len(words) == 0 {
+		return 0
+	}
+	return uint64(words[0])
+}
+
+// Kangaroo computes discrete logarithms known to lie in an interval with Pollard's kangaroo algorithm.
+type Kangaroo struct {
+	Group Group
+	// K is the number of distinct jumps. If zero, it is the smallest number of jumps whose mean size
+	// reaches Mean.
+	K int
+	// Jump returns the size of jump i, for i in [0, K). If nil, jump i is 2^i.
+	Jump func(i int) *big.Int
+	// Mean is the mean jump size K is chosen for when K is zero. If nil, it is half the square root of
+	// the interval width.
+	Mean *big.Int
+	// N is the number of jumps the tame kangaroo takes. If zero, it is four times the mean size of the
+	// jumps in use.
+	N int64
+}
+
+// maxKangarooJumps bounds the number of jumps tried when choosing K.
+const maxKangarooJumps = 1024
+
+// jumps returns the jump sizes and their mean for an interval of width w.
+func (kg *Kangaroo) jumps(w *big.Int) ([]*big.Int, *big.Int, error) {
+	jump := kg.Jump
+	if jump == nil {
+		jump = func(i int) *big.Int { return new(big.Int).Lsh(bigOne, uint(i)) }
+	}
+	var sizes []*big.Int
+	sum := new(big.Int)
+	add := func(i int) error {
+		size := jump(i)
+		if size.Sign() <= 0 {
+			return errors.Errorf("kangaroo: jump %d is not positive", i)
+		}
+		sizes = append(sizes, size)
+		sum.Add(sum, size)
+		return nil
+	}
+	if kg.K > 0 {
+		for i := 0; i < kg.K; i++ {
+			if err := add(i); err != nil {
+				return nil, nil, err
+			}
+		}
+	} else {
+		// Grow the jump set until sum/k >= num/den.
+		num, den := kg.Mean, big.NewInt(1)
+		if num == nil {
+			num, den = new(big.Int).Sqrt(w), big.NewInt(2)
+		}
+		lhs, rhs := new(big.Int), new(big.Int)
+		for {
+			if len(sizes) == maxKangarooJumps {
+				return nil, nil, errors.Errorf("kangaroo: %d jumps do not reach the mean jump size", maxKangarooJumps)
+			}
+			if err := add(len(sizes)); err != nil {
+				return nil, nil, err
+			}
+			lhs.Mul(sum, den)
+			rhs.Mul(num, big.NewInt(int64(len(sizes))))
+			if lhs.Cmp(rhs) >= 0 {
+				break
+			}
+		}
+	}
+	return sizes, sum.Quo(sum, big.NewInt(int64(len(sizes)))), nil
+}
+
+// Catch returns the x in [a, b] with g^x = y, or ErrNotFound if the wild kangaroo escapes.
+func (kg *Kangaroo) Catch(g, y GroupElement, a, b *big.Int) (*big.Int, error) {
+	if b.Cmp(a) < 0 {
+		return nil, errors.New("kangaroo: empty interval")
+	}
+	sizes, mean, err := kg.jumps(new(big.Int).Sub(b, a))
+	if err != nil {
+		return nil, err
+	}
+	steps := make([]GroupElement, len(sizes))
+	for i, s := range sizes {
+		steps[i] = kg.Group.Exp(g, s)
+	}
+	k := uint64(len(sizes))
+
+	n := big.NewInt(kg.N)
+	if kg.N == 0 {
+		n.Mul(mean, big.NewInt(4))
+	}
+	xT, yT := new(big.Int), kg.Group.Exp(g, b)
+	for i := new(big.Int); i.Cmp(n) < 0; i.Add(i, bigOne) {
+		j := kg.Group.Hash(yT) % k
+		xT.Add(xT, sizes[j])
+		yT = kg.Group.Mul(yT, steps[j])
+	}
+
+	limit := new(big.Int).Sub(b, a)
+	limit.Add(limit, xT)
+	xW, yW := new(big.Int), y
+	for xW.Cmp(limit) <= 0 {
+		if kg.Group.Equal(yW, yT) {
+			return xW.Sub(xT, xW).Add(xW, b), nil
+		}
+		j := kg.Group.Hash(yW) % k
+		xW.Add(xW, sizes[j])
+		yW = kg.Group.Mul(yW, steps[j])
+	}
+	return nil, ErrNotFound
+}
@@ -0,0 +1,66 @@
+package cryptopals
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestKangarooCatch(t *testing.T) {
+	group := Challenge58Group
+	tests := []struct {
+		name string
+		kg   *Kangaroo
+		a, b int64
+		x    int64
+	}{
+		{name: "default", kg: &Kangaroo{}, a: 0, b: 1 << 20, x: 705485},
+		{name: "offset", kg: &Kangaroo{}, a: 1 << 30, b: 1<<30 + 1<<24, x: 1<<30 + 12345678},
+		{name: "negative", kg: &Kangaroo{}, a: -1 << 20, b: 1 << 20, x: -98765},
+		{name: "lower bound", kg: &Kangaroo{}, a: 0, b: 1 << 20, x: 0},
+		{name: "upper bound", kg: &Kangaroo{}, a: 0, b: 1 << 20, x: 1 << 20},
+		{
+			name: "custom jumps",
+			kg: &Kangaroo{
+				K:    12,
+				Jump: func(i int) *big.Int { return big.NewInt(int64(3*i + 1)) },
+				N:    200,
+			},
+			a: 0, b: 1 << 16, x: 54321,
+		},
+		{
+			name: "custom jumps and mean",
+			kg: &Kangaroo{
+				Jump: func(i int) *big.Int { return big.NewInt(int64(3*i + 1)) },
+				Mean: big.NewInt(100),
+			},
+			a: 0, b: 1 << 16, x: 54321,
+		},
+		{name: "large mean", kg: &Kangaroo{Mean: big.NewInt(1 << 12)}, a: 0, b: 1 << 20, x: 705485},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.kg.Group = ModPGroup{P: group.P}
+			y := new(big.Int).Exp(group.G, big.NewInt(tt.x), group.P)
+			got, err := tt.kg.Catch(group.G, y, big.NewInt(tt.a), big.NewInt(tt.b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Int64() != tt.x {
+				t.Errorf("Catch() = %v, want %v", got, tt.x)
+			}
+		})
+	}
+}
+
+func TestKangarooEscape(t *testing.T) {
+	group := Challenge58Group
+	kg := &Kangaroo{Group: ModPGroup{P: group.P}}
+	y := new(big.Int).Exp(group.G, big.NewInt(1<<40), group.P)
+	if _, err := kg.Catch(group.G, y, big.NewInt(0), big.NewInt(1<<16)); err != ErrNotFound {
+		t.Errorf("Catch() error = %v, want %v", err, ErrNotFound)
+	}
+	kg.Jump = func(i int) *big.Int { return big.NewInt(1) }
+	if _, err := kg.Catch(group.G, y, big.NewInt(0), big.NewInt(1<<16)); err == nil {
+		t.Error("Catch() accepted jumps that never reach the mean jump size")
+	}
+}
@@ -0,0 +1,184 @@
+package cryptopals
+
+import (
+	"math/big"
+	"strings"
+)
+
+// Basis is an ordered set of lattice basis vectors, one per row, with exact rational entries.
+type Basis [][]*big.Rat
+
+// NewBasis returns the basis with the given integer rows.
+func NewBasis(rows ...[]int64) Basis {
+	b := make(Basis, len(rows))
+	for i, row := range rows {
+		b[i] = make([]*big.Rat, len(row))
+		for j, v := range row {
+			b[i][j] = new(big.Rat).SetInt64(v)
+		}
+	}
+	return b
+}
+
+// Clone returns a deep copy of b.
+func (b Basis) Clone() Basis {
+	c := make(Basis, len(b))
+	for i, row := range b {
+		c[i] = make([]*big.Rat, len(row))
+		for j, v := range row {
+			c[i][j] = new(big.Rat).Set(v)
+		}
+	}
+	return c
+}
+
+// String formats b one row per line.
+func (b Basis) String() string {
+	var sb strings.Builder
+	for _, row := range b {
+		sb.WriteString("[")
+		for j, v := range row {
+			if j > 0 {
+				sb.WriteString(" ")
+			}
+			sb.WriteString(v.RatString())
+		}
+		sb.WriteString("]\n")
+	}
+	return sb.String()
+}
+
+// dot returns the inner product of u and v.
+func dot(u, v []*big.Rat) *big.Rat {
+	sum, t := new(big.Rat), new(big.Rat)
+	for i := range u {
+		sum.Add(sum, t.Mul(u[i], v[i]))
+	}
+	return sum
+}
+
+// GramSchmidt returns the Gram-Schmidt orthogonalization b* of b and the coefficients
+// mu[i][j] = <b_i, b*_j> / <b*_j, b*_j> for j < i.
+func (b Basis) GramSchmidt() (Basis, [][]*big.Rat) {
+	bstar := make(Basis, len(b))
+	mu := make([][]*big.Rat, len(b))
+	norms := make([]*big.Rat, len(b))
+	t := new(big.Rat)
+	for i, v := range b {
+		mu[i] = make([]*big.Rat, i)
+		bstar[i] = make([]*big.Rat, len(v))
+		for k := range v {
+			bstar[i][k] = new(big.Rat).Set(v[k])
+		}
+		for j := 0; j < i; j++ {
+			mu[i][j] = new(big.Rat)
+			if norms[j].Sign() != 0 {
+				mu[i][j].Quo(dot(v, bstar[j]), norms[j])
+			}
+			for k := range bstar[i] {
+				bstar[i][k].Sub(bstar[i][k], t.Mul(mu[i][j], bstar[j][k]))
+			}
+		}
+		norms[i] = dot(bstar[i], bstar[i])
+	}
+	return bstar, mu
+}
+
+// roundRat returns x rounded to the nearest integer, with halves rounded up.
+func roundRat(x *big.Rat) *big.Int {
+	t := new(big.Rat).Add(x, big.NewRat(1, 2))
+	// Div rounds towards negative infinity for positive divisors.
+	return new(big.Int).Div(t.Num(), t.Denom())
+}
+
+// LLL returns an LLL-reduced copy of the linearly independent basis b with parameter delta in (1/4, 1);
+// 3/4 is the usual choice. The Gram-Schmidt data is updated incrementally on each size reduction and
+// swap rather than recomputed.
+func LLL(b Basis, delta *big.Rat) Basis {
+	b = b.Clone()
+	n := len(b)
+	if n < 2 {
+		return b
+	}
+	bstar, mu := b.GramSchmidt()
+	B := make([]*big.Rat, n)
+	for i := range bstar {
+		B[i] = dot(bstar[i], bstar[i])
+	}
+	t, qr := new(big.Rat), new(big.Rat)
+	reduce := func(k, j int) {
+		q := roundRat(mu[k][j])
+		if q.Sign() == 0 {
+			return
+		}
+		qr.SetInt(q)
+		for i := range b[k] {
+			b[k][i].Sub(b[k][i], t.Mul(qr, b[j][i]))
+		}
+		for l := 0; l < j; l++ {
+			mu[k][l].Sub(mu[k][l], t.Mul(qr, mu[j][l]))
+		}
+		mu[k][j].Sub(mu[k][j], qr)
+	}
+	for k := 1; k < n; {
+		reduce(k, k-1)
+		// Lovász condition: B_k >= (delta - mu_{k,k-1}^2) B_{k-1}.
+		lhs := new(big.Rat).Mul(mu[k][k-1], mu[k][k-1])
+		lhs.Sub(delta, lhs).Mul(lhs, B[k-1])
+		if B[k].Cmp(lhs) >= 0 {
+			for j := k - 2; j >= 0; j-- {
+				reduce(k, j)
+			}
+			k++
+			continue
+		}
+		// Swap b_k and b_{k-1} and update mu and B.
+		m := mu[k][k-1]
+		b[k], b[k-1] = b[k-1], b[k]
+		for j := 0; j < k-1; j++ {
+			mu[k][j], mu[k-1][j] = mu[k-1][j], mu[k][j]
+		}
+		bn := new(big.Rat).Mul(m, m)
+		bn.Mul(bn, B[k-1]).Add(bn, B[k])
+		newMu := new(big.Rat).Mul(m, B[k-1])
+		newMu.Quo(newMu, bn)
+		bk := new(big.Rat).Mul(B[k-1], B[k])
+		B[k] = bk.Quo(bk, bn)
+		B[k-1] = bn
+		mu[k][k-1] = newMu
+		for i := k + 1; i < n; i++ {
+			ti := mu[i][k]
+			nk := new(big.Rat).Mul(m, ti)
+			nk.Sub(mu[i][k-1], nk)
+			nk1 := new(big.Rat).Mul(newMu, nk)
+			nk1.Add(nk1, ti)
+			mu[i][k], mu[i][k-1] = nk, nk1
+		}
+		if k > 1 {
+			k--
+		}
+	}
+	return b
+}
+
+// IsLLLReduced reports whether b is size reduced and satisfies the Lovász condition for delta.
+func (b Basis) IsLLLReduced(delta *big.Rat) bool {
+	bstar, mu := b.GramSchmidt()
+	half := big.NewRat(1, 2)
+	abs := new(big.Rat)
+	for i := range mu {
+		for j := range mu[i] {
+			if abs.Abs(mu[i][j]).Cmp(half) > 0 {
+				return false
+			}
+		}
+	}
+	for k := 1; k < len(b); k++ {
+		rhs := new(big.Rat).Mul(mu[k][k-1], mu[k][k-1])
+		rhs.Sub(delta, rhs).Mul(rhs, dot(bstar[k-1], bstar[k-1]))
+		if dot(bstar[k], bstar[k]).Cmp(rhs) < 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+package cryptopals
+
+import (
+	"math/big"
+	mathrand "math/rand"
+	"testing"
+)
+
+// basisDet2 returns the squared volume of the lattice spanned by b, the product of the squared
+// Gram-Schmidt norms.
+func basisDet2(b Basis) *big.Rat {
+	bstar, _ := b.GramSchmidt()
+	d := big.NewRat(1, 1)
+	for _, v := range bstar {
+		d.Mul(d, dot(v, v))
+	}
+	return d
+}
+
+func TestGramSchmidt(t *testing.T) {
+	b := NewBasis([]int64{3, 1}, []int64{2, 2})
+	bstar, mu := b.GramSchmidt()
+	if got, want := mu[1][0], big.NewRat(8, 10); got.Cmp(want) != 0 {
+		t.Errorf("mu[1][0] = %v, want %v", got, want)
+	}
+	if d := dot(bstar[0], bstar[1]); d.Sign() != 0 {
+		t.Errorf("<b*_0, b*_1> = %v, want 0", d)
+	}
+	want := []*big.Rat{big.NewRat(-2, 5), big.NewRat(6, 5)}
+	for i := range want {
+		if bstar[1][i].Cmp(want[i]) != 0 {
+			t.Errorf("b*_1 = %v, want %v", bstar[1], want)
+			break
+		}
+	}
+}
+
+func TestLLL(t *testing.T) {
+	delta := big.NewRat(3, 4)
+	tests := []struct {
+		name string
+		in   Basis
+		want Basis
+	}{
+		{
+			// Wikipedia's worked example ends with (-1, 0, 2); rounding mu = 1/2 up gives (-2, 0, 1) instead.
+			name: "wikipedia",
+			in:   NewBasis([]int64{1, 1, 1}, []int64{-1, 0, 2}, []int64{3, 5, 6}),
+			want: NewBasis([]int64{0, 1, 0}, []int64{1, 0, 1}, []int64{-2, 0, 1}),
+		},
+		{
+			name: "2d",
+			in:   NewBasis([]int64{201, 37}, []int64{1648, 297}),
+			want: NewBasis([]int64{1, 32}, []int64{40, 1}),
+		},
+		{
+			name: "reduced",
+			in:   NewBasis([]int64{1, 0}, []int64{0, 1}),
+			want: NewBasis([]int64{1, 0}, []int64{0, 1}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := LLL(tt.in, delta)
+			if got.String() != tt.want.String() {
+				t.Errorf("LLL() =\n%vwant\n%v", got, tt.want)
+			}
+			if !got.IsLLLReduced(delta) {
+				t.Error("result is not LLL reduced")
+			}
+		})
+	}
+}
+
+func TestLLLRandom(t *testing.T) {
+	delta := big.NewRat(99, 100)
+	rng := mathrand.New(mathrand.NewSource(1))
+	for n := 2; n <= 8; n++ {
+		rows := make([][]int64, n)
+		for i := range rows {
+			rows[i] = make([]int64, n)
+			for j := range rows[i] {
+				rows[i][j] = rng.Int63n(2001) - 1000
+			}
+		}
+		b := NewBasis(rows...)
+		got := LLL(b, delta)
+		if !got.IsLLLReduced(delta) {
+			t.Errorf("LLL() of %d-dimensional basis is not reduced:\n%v", n, got)
+		}
+		if basisDet2(got).Cmp(basisDet2(b)) != 0 {
+			t.Errorf("LLL() changed the lattice volume of %d-dimensional basis", n)
+		}
+	}
+	if b := NewBasis([]int64{5, 7}); LLL(b, delta).String() != b.String() {
+		t.Error("LLL() changed a one-vector basis")
+	}
+}
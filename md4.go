@@ -0,0 +1,156 @@
+package cryptopals
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+const (
+	// MD4Size is the size of an MD4 digest in bytes.
+	MD4Size = 16
+	// MD4BlockSize is the block size of MD4 in bytes.
+	MD4BlockSize = 64
+)
+
+// MD4IV is the initial MD4 state.
+var MD4IV = [4]uint32{0x67452301, 0xefcdab89, 0x98badcfe, 0x10325476}
+
+// MD4F is the round 1 boolean function: if x then y else z.
+func MD4F(x, y, z uint32) uint32 { return x&y | ^x&z }
+
+// MD4G is the round 2 boolean function: the majority of x, y and z.
+func MD4G(x, y, z uint32) uint32 { return x&y | x&z | y&z }
+
+// MD4H is the round 3 boolean function: the parity of x, y and z.
+func MD4H(x, y, z uint32) uint32 { return x ^ y ^ z }
+
+// MD4Round1 computes one round 1 step: (a + F(b, c, d) + m) <<< s.
+func MD4Round1(a, b, c, d, m uint32, s int) uint32 {
+	return bits.RotateLeft32(a+MD4F(b, c, d)+m, s)
+}
+
+// MD4Round2 computes one round 2 step: (a + G(b, c, d) + m + 0x5a827999) <<< s.
+func MD4Round2(a, b, c, d, m uint32, s int) uint32 {
+	return bits.RotateLeft32(a+MD4G(b, c, d)+m+0x5a827999, s)
+}
+
+// MD4Round3 computes one round 3 step: (a + H(b, c, d) + m + 0x6ed9eba1) <<< s.
+func MD4Round3(a, b, c, d, m uint32, s int) uint32 {
+	return bits.RotateLeft32(a+MD4H(b, c, d)+m+0x6ed9eba1, s)
+}
+
+var (
+	md4Round1Shifts = [4]int{3, 7, 11, 19}
+	md4Round2Shifts = [4]int{3, 5, 9, 13}
+	md4Round3Shifts = [4]int{3, 9, 11, 15}
+	md4Round2Order  = [16]int{0, 4, 8, 12, 1, 5, 9, 13, 2, 6, 10, 14, 3, 7, 11, 15}
+	md4Round3Order  = [16]int{0, 8, 4, 12, 2, 10, 6, 14, 1, 9, 5, 13, 3, 11, 7, 15}
+)
+
+// MD4Words decodes a 64-byte block into little-endian message words.
+func MD4Words(block []byte) [16]uint32 {
+	var m [16]uint32
+	for i := range m {
+		m[i] = binary.LittleEndian.Uint32(block[4*i:])
+	}
+	return m
+}
+
+// MD4Block encodes message words into a 64-byte block.
+func MD4Block(m [16]uint32) []byte {
+	block := make([]byte, MD4BlockSize)
+	for i, w := range m {
+		binary.LittleEndian.PutUint32(block[4*i:], w)
+	}
+	return block
+}
+
+// MD4Compress runs the MD4 compression function on one block of message words.
+func MD4Compress(state [4]uint32, m [16]uint32) [4]uint32 {
+	a, b, c, d := state[0], state[1], state[2], state[3]
+	for i := 0; i < 16; i += 4 {
+		a = MD4Round1(a, b, c, d, m[i], md4Round1Shifts[0])
+		d = MD4Round1(d, a, b, c, m[i+1], md4Round1Shifts[1])
+		c = MD4Round1(c, d, a, b, m[i+2], md4Round1Shifts[2])
+		b = MD4Round1(b, c, d, a, m[i+3], md4Round1Shifts[3])
+	}
+	for i := 0; i < 16; i += 4 {
+		a = MD4Round2(a, b, c, d, m[md4Round2Order[i]], md4Round2Shifts[0])
+		d = MD4Round2(d, a, b, c, m[md4Round2Order[i+1]], md4Round2Shifts[1])
+		c = MD4Round2(c, d, a, b, m[md4Round2Order[i+2]], md4Round2Shifts[2])
+		b = MD4Round2(b, c, d, a, m[md4Round2Order[i+3]], md4Round2Shifts[3])
+	}
+	for i := 0; i < 16; i += 4 {
+		a = MD4Round3(a, b, c, d, m[md4Round3Order[i]], md4Round3Shifts[0])
+		d = MD4Round3(d, a, b, c, m[md4Round3Order[i+1]], md4Round3Shifts[1])
+		c = MD4Round3(c, d, a, b, m[md4Round3Order[i+2]], md4Round3Shifts[2])
+		b = MD4Round3(b, c, d, a, m[md4Round3Order[i+3]], md4Round3Shifts[3])
+	}
+	return [4]uint32{state[0] + a, state[1] + b, state[2] + c, state[3] + d}
+}
+
+// MD4Padding returns the padding appended to a message of length n bytes.
+func MD4Padding(n uint64) []byte {
+	pad := []byte{0x80}
+	for (n+uint64(len(pad))+8)%MD4BlockSize != 0 {
+		pad = append(pad, 0)
+	}
+	var length [8]byte
+	binary.LittleEndian.PutUint64(length[:], n*8)
+	return append(pad, length[:]...)
+}
+
+type md4Digest struct {
+	state [4]uint32
+	buf   []byte
+	n     uint64
+}
+
+// NewMD4 returns a hash.Hash computing MD4.
+func NewMD4() hash.Hash {
+	d := &md4Digest{}
+	d.Reset()
+	return d
+}
+
+func (d *md4Digest) Reset() {
+	d.state = MD4IV
+	d.buf = nil
+	d.n = 0
+}
+
+func (d *md4Digest) Size() int { return MD4Size }
+
+func (d *md4Digest) BlockSize() int { return MD4BlockSize }
+
+func (d *md4Digest) Write(p []byte) (int, error) {
+	d.n += uint64(len(p))
+	d.buf = append(d.buf, p...)
+	for len(d.buf) >= MD4BlockSize {
+		d.state = MD4Compress(d.state, MD4Words(d.buf))
+		d.buf = d.buf[MD4BlockSize:]
+	}
+	return len(p), nil
+}
+
+func (d *md4Digest) Sum(in []byte) []byte {
+	state := d.state
+	tail := append(append([]byte{}, d.buf...), MD4Padding(d.n)...)
+	for i := 0; i < len(tail); i += MD4BlockSize {
+		state = MD4Compress(state, MD4Words(tail[i:]))
+	}
+	for _, w := range state {
+		in = binary.LittleEndian.AppendUint32(in, w)
+	}
+	return in
+}
+
+// MD4Sum returns the MD4 digest of data.
+func MD4Sum(data []byte) [MD4Size]byte {
+	var sum [MD4Size]byte
+	h := NewMD4()
+	h.Write(data)
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
@@ -0,0 +1,108 @@
+package cryptopals
+
+import (
+	"crypto/aes"
+	"encoding/binary"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// MDHash is a toy Merkle-Damgård hash with a deliberately small state.
+//
+// Its compression function encrypts the state with AES-ECB, keyed by the message block, and truncates the
+// result back to the state size. Messages are padded with a 1 bit, zeros and the 64-bit message length.
+type MDHash struct {
+	// IV is the initial state.
+	IV []byte
+
+	calls atomic.Int64
+}
+
+// MDHashBlockSize is the block size of MDHash in bytes.
+const MDHashBlockSize = aes.BlockSize
+
+// NewMDHash returns a toy hash with a state of the given number of bits.
+// If iv is nil a fixed IV is derived from the state size.
+func NewMDHash(bits int, iv []byte) (*MDHash, error) {
+	if bits < 8 || bits > 8*aes.BlockSize || bits%8 != 0 {
+		return nil, errors.Errorf("unsupported state size %d", bits)
+	}
+	if iv == nil {
+		iv = make([]byte, bits/8)
+		for i := range iv {
+			iv[i] = byte(bits + i)
+		}
+	}
+	if len(iv) != bits/8 {
+		return nil, ErrMismatchedLength
+	}
+	return &MDHash{IV: iv}, nil
+}
+
+// Size returns the state size in bytes.
+func (h *MDHash) Size() int {
+	return len(h.IV)
+}
+
+// Calls returns the number of times the compression function has run.
+func (h *MDHash) Calls() int64 {
+	return h.calls.Load()
+}
+
+// ResetCalls resets the compression function counter.
+func (h *MDHash) ResetCalls() {
+	h.calls.Store(0)
+}
+
+// Compress runs the compression function on a single block.
+func (h *MDHash) Compress(state, block []byte) []byte {
+	h.calls.Add(1)
+	c, err := EncryptAESECB(state, block)
+	if err != nil {
+		panic(err)
+	}
+	return c[:len(state)]
+}
+
+// Iterate runs the compression function over whole blocks of msg starting from state, without padding.
+func (h *MDHash) Iterate(state, msg []byte) []byte {
+	if len(msg)%MDHashBlockSize != 0 {
+		panic("MDHash: message is not a whole number of blocks")
+	}
+	for i := 0; i < len(msg); i += MDHashBlockSize {
+		state = h.Compress(state, msg[i:i+MDHashBlockSize])
+	}
+	return state
+}
+
+// MDPadding returns the padding appended to a message of length n bytes.
+func MDPadding(n int) []byte {
+	pad := []byte{0x80}
+	for (n+len(pad)+8)%MDHashBlockSize != 0 {
+		pad = append(pad, 0)
+	}
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(n)*8)
+	return append(pad, length[:]...)
+}
+
+// Sum returns the padded hash of msg.
+func (h *MDHash) Sum(msg []byte) []byte {
+	padded := append(append([]byte{}, msg...), MDPadding(len(msg))...)
+	return h.Iterate(h.IV, padded)
+}
+
+// FindCollision searches for two distinct blocks that compress to the same state from state, using the birthday bound.
+// It returns the blocks and the resulting state.
+func (h *MDHash) FindCollision(state []byte) (a, b, next []byte) {
+	seen := make(map[string][]byte)
+	for {
+		block := RandomNBytes(MDHashBlockSize)
+		out := h.Compress(state, block)
+		if prev, ok := seen[string(out)]; ok && string(prev) != string(block) {
+			return prev, block, out
+		}
+		seen[string(out)] = block
+	}
+}
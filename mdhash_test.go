@@ -0,0 +1,70 @@
+package cryptopals
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMDPadding(t *testing.T) {
+	for n := 0; n < 40; n++ {
+		pad := MDPadding(n)
+		if (n+len(pad))%MDHashBlockSize != 0 {
+			t.Errorf("MDPadding(%d) has length %d, want total a multiple of %d", n, len(pad), MDHashBlockSize)
+		}
+		if pad[0] != 0x80 || len(pad) < 9 || len(pad) > 8+MDHashBlockSize {
+			t.Errorf("MDPadding(%d) = %x", n, pad)
+		}
+	}
+}
+
+func TestMDHash(t *testing.T) {
+	tests := []struct {
+		name    string
+		bits    int
+		wantErr bool
+	}{
+		{"16", 16, false},
+		{"24", 24, false},
+		{"128", 128, false},
+		{"odd", 12, true},
+		{"too big", 136, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := NewMDHash(tt.bits, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewMDHash() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			a := h.Sum([]byte("hello"))
+			if len(a) != tt.bits/8 {
+				t.Errorf("len(Sum()) = %d, want %d", len(a), tt.bits/8)
+			}
+			if b := h.Sum([]byte("hello")); !bytes.Equal(a, b) {
+				t.Errorf("Sum() is not deterministic: %x != %x", a, b)
+			}
+			if h.Calls() != 2 {
+				t.Errorf("Calls() = %d, want 2", h.Calls())
+			}
+		})
+	}
+}
+
+func TestMDHashFindCollision(t *testing.T) {
+	h, err := NewMDHash(16, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b, next := h.FindCollision(h.IV)
+	if bytes.Equal(a, b) {
+		t.Fatalf("FindCollision() returned identical blocks %x", a)
+	}
+	if got := h.Compress(h.IV, a); !bytes.Equal(got, next) {
+		t.Errorf("Compress(a) = %x, want %x", got, next)
+	}
+	if got := h.Compress(h.IV, b); !bytes.Equal(got, next) {
+		t.Errorf("Compress(b) = %x, want %x", got, next)
+	}
+}
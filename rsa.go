@@ -0,0 +1,192 @@
+package cryptopals
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrNoInverse is returned when a value has no modular inverse.
+	ErrNoInverse = errors.New("no modular inverse")
+	// ErrMessageTooLong is returned when a message does not fit under the modulus.
+	ErrMessageTooLong = errors.New("message too long")
+)
+
+var bigOne = big.NewInt(1)
+
+// egcd runs the extended Euclidean algorithm and returns g, x, y such that ax + by = g = gcd(a, b).
+func egcd(a, b *big.Int) (g, x, y *big.Int) {
+	oldR, r := new(big.Int).Set(a), new(big.Int).Set(b)
+	oldS, s := big.NewInt(1), big.NewInt(0)
+	oldT, t := big.NewInt(0), big.NewInt(1)
+	q, tmp := new(big.Int), new(big.Int)
+	for r.Sign() != 0 {
+		q.Quo(oldR, r)
+		oldR, r = r, oldR.Sub(oldR, tmp.Mul(q, r))
+		oldS, s = s, oldS.Sub(oldS, tmp.Mul(q, s))
+		oldT, t = t, oldT.Sub(oldT, tmp.Mul(q, t))
+	}
+	return oldR, oldS, oldT
+}
+
+// InvMod returns the inverse of a modulo m.
+func InvMod(a, m *big.Int) (*big.Int, error) {
+	if m.Sign() <= 0 {
+		return nil, ErrNoInverse
+	}
+	g, x, _ := egcd(new(big.Int).Mod(a, m), m)
+	if g.Cmp(bigOne) != 0 {
+		return nil, ErrNoInverse
+	}
+	return x.Mod(x, m), nil
+}
+
+// RSAPublicKey is a textbook RSA public key.
+type RSAPublicKey struct {
+	N *big.Int
+	E *big.Int
+}
+
+// RSAPrivateKey is a textbook RSA private key. The CRT values are optional.
+type RSAPrivateKey struct {
+	RSAPublicKey
+	D *big.Int
+
+	P, Q *big.Int
+	Dp   *big.Int // d mod (p-1)
+	Dq   *big.Int // d mod (q-1)
+	Qinv *big.Int // q^-1 mod p
+}
+
+// GenerateKey generates a textbook RSA key with a modulus of the given size and public exponent e.
+// Primes are regenerated until e is invertible modulo the totient.
+func GenerateKey(bits, e int) (*RSAPrivateKey, error) {
+	if bits < 16 {
+		return nil, errors.New("key size too small")
+	}
+	if e < 3 || e%2 == 0 {
+		return nil, errors.New("public exponent must be odd and at least 3")
+	}
+	E := big.NewInt(int64(e))
+	for {
+		p, err := rand.Prime(rand.Reader, bits-bits/2)
+		if err != nil {
+			return nil, err
+		}
+		q, err := rand.Prime(rand.Reader, bits/2)
+		if err != nil {
+			return nil, err
+		}
+		if p.Cmp(q) == 0 {
+			continue
+		}
+		pm1 := new(big.Int).Sub(p, bigOne)
+		qm1 := new(big.Int).Sub(q, bigOne)
+		phi := new(big.Int).Mul(pm1, qm1)
+		d, err := InvMod(E, phi)
+		if err != nil {
+			continue
+		}
+		k := &RSAPrivateKey{
+			RSAPublicKey: RSAPublicKey{N: new(big.Int).Mul(p, q), E: E},
+			D:            d,
+			P:            p,
+			Q:            q,
+		}
+		if err := k.Precompute(); err != nil {
+			continue
+		}
+		return k, nil
+	}
+}
+
+// Precompute fills in the CRT values from P, Q and D.
+func (k *RSAPrivateKey) Precompute() error {
+	if k.P == nil || k.Q == nil {
+		return ErrEmpty
+	}
+	qinv, err := InvMod(k.Q, k.P)
+	if err != nil {
+		return err
+	}
+	k.Dp = new(big.Int).Mod(k.D, new(big.Int).Sub(k.P, bigOne))
+	k.Dq = new(big.Int).Mod(k.D, new(big.Int).Sub(k.Q, bigOne))
+	k.Qinv = qinv
+	return nil
+}
+
+// Public returns the public half of the key.
+func (k *RSAPrivateKey) Public() *RSAPublicKey {
+	return &k.RSAPublicKey
+}
+
+// Size returns the modulus size in bytes.
+func (k *RSAPublicKey) Size() int {
+	return (k.N.BitLen() + 7) / 8
+}
+
+// EncryptInt returns m**e mod n.
+func (k *RSAPublicKey) EncryptInt(m *big.Int) *big.Int {
+	return new(big.Int).Exp(m, k.E, k.N)
+}
+
+// Encrypt encrypts the big-endian integer in m. The ciphertext is left-padded with zeros to the modulus size.
+func (k *RSAPublicKey) Encrypt(m []byte) ([]byte, error) {
+	mi := new(big.Int).SetBytes(m)
+	if mi.Cmp(k.N) >= 0 {
+		return nil, ErrMessageTooLong
+	}
+	return k.EncryptInt(mi).FillBytes(make([]byte, k.Size())), nil
+}
+
+// DecryptInt returns c**d mod n, using the CRT values when they are available.
+func (k *RSAPrivateKey) DecryptInt(c *big.Int) *big.Int {
+	if k.Dp == nil || k.Dq == nil || k.Qinv == nil {
+		return new(big.Int).Exp(c, k.D, k.N)
+	}
+	// m1 = c**dp mod p, m2 = c**dq mod q, h = qinv(m1 - m2) mod p, m = m2 + hq
+	m1 := new(big.Int).Exp(c, k.Dp, k.P)
+	m2 := new(big.Int).Exp(c, k.Dq, k.Q)
+	h := m1.Sub(m1, m2)
+	h.Mul(h, k.Qinv).Mod(h, k.P)
+	return h.Mul(h, k.Q).Add(h, m2)
+}
+
+// Decrypt decrypts c. As with any textbook scheme, leading zero bytes of the plaintext are not preserved.
+func (k *RSAPrivateKey) Decrypt(c []byte) ([]byte, error) {
+	ci := new(big.Int).SetBytes(c)
+	if ci.Cmp(k.N) >= 0 {
+		return nil, ErrMessageTooLong
+	}
+	return k.DecryptInt(ci).Bytes(), nil
+}
+
+// RSAPrivateKeyFromStdlib converts a crypto/rsa key into a textbook key.
+func RSAPrivateKeyFromStdlib(priv *rsa.PrivateKey) (*RSAPrivateKey, error) {
+	if len(priv.Primes) != 2 {
+		return nil, errors.New("only two-prime keys are supported")
+	}
+	k := &RSAPrivateKey{
+		RSAPublicKey: RSAPublicKey{N: priv.N, E: big.NewInt(int64(priv.E))},
+		D:            priv.D,
+		P:            priv.Primes[0],
+		Q:            priv.Primes[1],
+	}
+	return k, k.Precompute()
+}
+
+// Stdlib converts the key into a crypto/rsa key.
+func (k *RSAPrivateKey) Stdlib() *rsa.PrivateKey {
+	priv := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: k.N, E: int(k.E.Int64())},
+		D:         k.D,
+	}
+	if k.P != nil && k.Q != nil {
+		priv.Primes = []*big.Int{k.P, k.Q}
+		priv.Precompute()
+	}
+	return priv
+}
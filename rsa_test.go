@@ -0,0 +1,127 @@
+package cryptopals
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+func TestInvMod(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, m    int64
+		want    int64
+		wantErr bool
+	}{
+		{"example", 17, 3120, 2753, false},
+		{"negative", -3, 7, 2, false},
+		{"one", 1, 5, 1, false},
+		{"not coprime", 6, 9, 0, true},
+		{"zero modulus", 3, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := InvMod(big.NewInt(tt.a), big.NewInt(tt.m))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("InvMod() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Int64() != tt.want {
+				t.Errorf("InvMod() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateKey(t *testing.T) {
+	tests := []struct {
+		bits, e int
+	}{
+		{64, 3},
+		{512, 3},
+		{1024, 65537},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d-%d", tt.bits, tt.e), func(t *testing.T) {
+			k, err := GenerateKey(tt.bits, tt.e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if k.N.BitLen() != tt.bits {
+				t.Errorf("N.BitLen() = %v, want %v", k.N.BitLen(), tt.bits)
+			}
+			m, err := rand.Int(rand.Reader, k.N)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := k.EncryptInt(m)
+			if got := k.DecryptInt(c); got.Cmp(m) != 0 {
+				t.Errorf("DecryptInt() = %v, want %v", got, m)
+			}
+			if got := new(big.Int).Exp(c, k.D, k.N); got.Cmp(m) != 0 {
+				t.Errorf("c**d = %v, want %v", got, m)
+			}
+		})
+	}
+}
+
+func TestRSAStdlibInterop(t *testing.T) {
+	std, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := RSAPrivateKeyFromStdlib(std)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A raw PKCS#1 v1.5 signature from crypto/rsa is s = EM**d, so our public operation must recover EM.
+	msg := []byte("raw interop check")
+	sig, err := rsa.SignPKCS1v15(nil, std, crypto.Hash(0), msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	em, err := k.Public().Encrypt(sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append([]byte{0x00, 0x01}, bytes.Repeat([]byte{0xff}, k.Size()-3-len(msg))...)
+	want = append(append(want, 0x00), msg...)
+	if !bytes.Equal(em, want) {
+		t.Errorf("Encrypt(sig) = %x, want %x", em, want)
+	}
+	if got := k.DecryptInt(new(big.Int).SetBytes(em)); got.Cmp(new(big.Int).SetBytes(sig)) != 0 {
+		t.Errorf("DecryptInt(em) = %x, want %x", got, sig)
+	}
+
+	// And a key generated here must validate as a crypto/rsa key.
+	ours, err := GenerateKey(2048, 65537)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ours.Stdlib().Validate(); err != nil {
+		t.Errorf("Stdlib().Validate() error = %v", err)
+	}
+}
+
+func ExampleChallenge39() {
+	k, err := GenerateKey(1024, 3)
+	if err != nil {
+		fmt.Println(err)
+	}
+	c, err := k.Public().Encrypt([]byte("attack at dawn"))
+	if err != nil {
+		fmt.Println(err)
+	}
+	p, err := k.Decrypt(c)
+	fmt.Printf("%q %v\n", p, err)
+	// output:
+	// "attack at dawn" <nil>
+}
@@ -0,0 +1,317 @@
+package cryptopals
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"math/big"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// NISTPrime is the 1536-bit MODP group prime used by the Diffie-Hellman and SRP challenges.
+var NISTPrime, _ = new(big.Int).SetString("ffffffffffffffffc90fdaa22168c234c4c6628b80dc1cd129024e088a67cc74020bbea63b139b22514a08798e3404ddef9519b3cd3a431b302b0a6df25f14374fe1356d6d51c245e485b576625e7ec6f44c42e9a637ed6b0bff5cb6f406b7edee386bfb5a899fa5ae9f24117c4b1fe649286651ece45b3dc2007cb8a163bf0598da48361c55d39a69163fa8fd24cf5f83655d23dca3ad961c62f356208552bb9ed529077096966d670c354e4abc9804f1746c08ca237327ffffffffffffffff", 16)
+
+// NISTGenerator is the generator paired with NISTPrime.
+var NISTGenerator = big.NewInt(2)
+
+// SimpleSRPServer is the server side of the simplified SRP handshake.
+type SimpleSRPServer interface {
+	// Handshake receives the client's email and public key and returns the salt, public key and u.
+	Handshake(email string, A *big.Int) (salt []byte, B, u *big.Int, err error)
+	// Verify checks the client's proof of the shared key.
+	Verify(mac []byte) bool
+}
+
+// SimpleSRPClient is the client side of the simplified SRP handshake.
+type SimpleSRPClient struct {
+	Email    string
+	Password string
+
+	a *big.Int
+	A *big.Int
+}
+
+// NewSimpleSRPClient returns a client with a fresh ephemeral key pair.
+func NewSimpleSRPClient(email, password string) (*SimpleSRPClient, error) {
+	a, err := rand.Int(rand.Reader, NISTPrime)
+	if err != nil {
+		return nil, err
+	}
+	return &SimpleSRPClient{
+		Email:    email,
+		Password: password,
+		a:        a,
+		A:        new(big.Int).Exp(NISTGenerator, a, NISTPrime),
+	}, nil
+}
+
+// Login performs the simplified SRP handshake against srv and reports whether the server accepted the proof.
+func (c *SimpleSRPClient) Login(srv SimpleSRPServer) (bool, error) {
+	salt, B, u, err := srv.Handshake(c.Email, c.A)
+	if err != nil {
+		return false, errors.Wrap(err, "handshake")
+	}
+	// S = B**(a + ux) % n
+	x := srpPrivateKey(salt, c.Password)
+	e := new(big.Int).Mul(u, x)
+	e.Add(e, c.a)
+	S := new(big.Int).Exp(B, e, NISTPrime)
+	return srv.Verify(srpProof(S, salt)), nil
+}
+
+// srpPrivateKey computes x = SHA256(salt|password).
+func srpPrivateKey(salt []byte, password string) *big.Int {
+	h := sha256.New()
+	h.Write(salt)
+	io.WriteString(h, password)
+	return new(big.Int).SetBytes(h.Sum(nil))
+}
+
+// srpProof computes HMAC-SHA256(SHA256(S), salt).
+func srpProof(S *big.Int, salt []byte) []byte {
+	K := sha256.Sum256(S.Bytes())
+	mac := hmac.New(sha256.New, K[:])
+	mac.Write(salt)
+	return mac.Sum(nil)
+}
+
+// SimpleSRPHonestServer is a simplified SRP server that knows the password verifier for a single user.
+type SimpleSRPHonestServer struct {
+	Email string
+
+	salt []byte
+	v    *big.Int
+	S    *big.Int
+}
+
+// NewSimpleSRPHonestServer returns a server that stores a verifier for the given credentials.
+func NewSimpleSRPHonestServer(email, password string) *SimpleSRPHonestServer {
+	salt := RandomNBytes(16)
+	x := srpPrivateKey(salt, password)
+	return &SimpleSRPHonestServer{
+		Email: email,
+		salt:  salt,
+		v:     new(big.Int).Exp(NISTGenerator, x, NISTPrime),
+	}
+}
+
+// Handshake satisfies the SimpleSRPServer interface.
+func (s *SimpleSRPHonestServer) Handshake(email string, A *big.Int) ([]byte, *big.Int, *big.Int, error) {
+	if email != s.Email {
+		return nil, nil, nil, ErrNotFound
+	}
+	b, err := rand.Int(rand.Reader, NISTPrime)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	u, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// S = (A * v ** u)**b % n
+	S := new(big.Int).Exp(s.v, u, NISTPrime)
+	S.Mul(S, A).Mod(S, NISTPrime)
+	s.S = S.Exp(S, b, NISTPrime)
+	return s.salt, new(big.Int).Exp(NISTGenerator, b, NISTPrime), u, nil
+}
+
+// Verify satisfies the SimpleSRPServer interface.
+func (s *SimpleSRPHonestServer) Verify(mac []byte) bool {
+	if s.S == nil {
+		return false
+	}
+	return hmac.Equal(mac, srpProof(s.S, s.salt))
+}
+
+// SimpleSRPMallory is a man-in-the-middle posing as a simplified SRP server.
+// It chooses b, u and the salt itself and records the client's proof for offline cracking.
+type SimpleSRPMallory struct {
+	Salt []byte
+	B    *big.Int
+	U    *big.Int
+
+	b   *big.Int
+	A   *big.Int
+	MAC []byte
+}
+
+// NewSimpleSRPMallory returns a Mallory that picks b = 1, u = 1 and an empty salt, which makes S = A * g**x.
+func NewSimpleSRPMallory() *SimpleSRPMallory {
+	return NewSimpleSRPMalloryWithParams(big.NewInt(1), big.NewInt(1), nil)
+}
+
+// NewSimpleSRPMalloryWithParams returns a Mallory that uses the provided b, u and salt.
+func NewSimpleSRPMalloryWithParams(b, u *big.Int, salt []byte) *SimpleSRPMallory {
+	return &SimpleSRPMallory{
+		Salt: salt,
+		B:    new(big.Int).Exp(NISTGenerator, b, NISTPrime),
+		U:    u,
+		b:    b,
+	}
+}
+
+// Handshake satisfies the SimpleSRPServer interface.
+func (m *SimpleSRPMallory) Handshake(email string, A *big.Int) ([]byte, *big.Int, *big.Int, error) {
+	m.A = A
+	return m.Salt, m.B, m.U, nil
+}
+
+// Verify satisfies the SimpleSRPServer interface. It records the proof and always reports success.
+func (m *SimpleSRPMallory) Verify(mac []byte) bool {
+	m.MAC = mac
+	return true
+}
+
+// Crack runs an offline dictionary attack over the words in the named file against the captured proof.
+func (m *SimpleSRPMallory) Crack(ctx context.Context, wordlist string) (string, error) {
+	if m.A == nil || m.MAC == nil {
+		return "", ErrEmpty
+	}
+	f, err := os.Open(wordlist)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return CrackSimpleSRP(ctx, f, m.A, m.b, m.U, m.Salt, m.MAC)
+}
+
+// CrackSimpleSRP tries each newline-separated password in words against a captured simplified SRP proof.
+// The client's public key A and the b, u and salt Mallory chose are required. Candidates are checked
+// in parallel and the search stops at the first match or when ctx is cancelled.
+func CrackSimpleSRP(ctx context.Context, words io.Reader, A, b, u *big.Int, salt, mac []byte) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	candidates := make(chan string)
+	found := make(chan string, 1)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pw := range candidates {
+				// S = (A * g**(ux))**b % n
+				x := srpPrivateKey(salt, pw)
+				S := new(big.Int).Exp(NISTGenerator, x.Mul(x, u), NISTPrime)
+				S.Mul(S, A).Mod(S, NISTPrime)
+				S.Exp(S, b, NISTPrime)
+				if hmac.Equal(mac, srpProof(S, salt)) {
+					select {
+					case found <- pw:
+					default:
+					}
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+
+	s := bufio.NewScanner(words)
+	var scanErr error
+feed:
+	for s.Scan() {
+		select {
+		case candidates <- s.Text():
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	if ctx.Err() == nil {
+		scanErr = s.Err()
+	}
+	close(candidates)
+	wg.Wait()
+
+	select {
+	case pw := <-found:
+		return pw, nil
+	default:
+	}
+	if scanErr != nil {
+		return "", scanErr
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return "", ErrNotFound
+}
+
+// CRT solves x = residues[i] mod moduli[i] for pairwise coprime moduli.
+// It returns the smallest non-negative solution together with the product of the moduli.
+func CRT(residues, moduli []*big.Int) (x, n *big.Int, err error) {
+	if len(residues) != len(moduli) {
+		return nil, nil, ErrMismatchedLength
+	}
+	if len(moduli) == 0 {
+		return nil, nil, ErrEmpty
+	}
+	n = big.NewInt(1)
+	for _, m := range moduli {
+		n.Mul(n, m)
+	}
+	x = new(big.Int)
+	for i, m := range moduli {
+		ms := new(big.Int).Quo(n, m)
+		inv, err := InvMod(ms, m)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "modulus %d is not coprime with the others", i)
+		}
+		term := new(big.Int).Mul(residues[i], ms)
+		x.Add(x, term.Mul(term, inv))
+	}
+	return x.Mod(x, n), n, nil
+}
+
+// IRoot returns the integer part of the k-th root of x, computed with Newton's method.
+func IRoot(x *big.Int, k int) *big.Int {
+	if x.Sign() < 0 || k < 1 {
+		panic("IRoot: invalid argument")
+	}
+	if x.Sign() == 0 || k == 1 {
+		return new(big.Int).Set(x)
+	}
+	K := big.NewInt(int64(k))
+	Km1 := big.NewInt(int64(k - 1))
+	// start above the root: 2**ceil(bitlen/k) > x**(1/k)
+	r := new(big.Int).Lsh(bigOne, uint((x.BitLen()+k-1)/k))
+	t := new(big.Int)
+	for {
+		// y = ((k-1)r + x / r**(k-1)) / k
+		t.Exp(r, Km1, nil)
+		t.Quo(x, t)
+		y := new(big.Int).Mul(r, Km1)
+		y.Add(y, t).Quo(y, K)
+		if y.Cmp(r) >= 0 {
+			return r
+		}
+		r = y
+	}
+}
+
+// HastadBroadcast recovers a message that was encrypted with textbook RSA under public exponent e
+// for at least e recipients with pairwise coprime moduli.
+func HastadBroadcast(ciphertexts, moduli []*big.Int, e int) (*big.Int, error) {
+	if len(ciphertexts) != len(moduli) {
+		return nil, ErrMismatchedLength
+	}
+	if e < 2 || len(moduli) < e {
+		return nil, errors.Errorf("need at least e = %d ciphertexts, have %d", e, len(moduli))
+	}
+	c, _, err := CRT(ciphertexts, moduli)
+	if err != nil {
+		return nil, err
+	}
+	m := IRoot(c, e)
+	if new(big.Int).Exp(m, big.NewInt(int64(e)), nil).Cmp(c) != 0 {
+		return nil, errors.Wrap(ErrNotFound, "combined ciphertext is not a perfect power")
+	}
+	return m, nil
+}
@@ -0,0 +1,179 @@
+package cryptopals
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestSimpleSRP(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		attempt  string
+		want     bool
+	}{
+		{"correct password", "hunter2", "hunter2", true},
+		{"wrong password", "hunter2", "hunter3", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewSimpleSRPHonestServer("alice@example.com", tt.password)
+			c, err := NewSimpleSRPClient("alice@example.com", tt.attempt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := c.Login(srv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Login() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrackSimpleSRP(t *testing.T) {
+	tests := []struct {
+		name    string
+		mallory *SimpleSRPMallory
+	}{
+		{"trivial params", NewSimpleSRPMallory()},
+		{"chosen params", NewSimpleSRPMalloryWithParams(big.NewInt(12345), big.NewInt(678), []byte("pepper"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewSimpleSRPClient("alice@example.com", "trustno1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.Login(tt.mallory); err != nil {
+				t.Fatal(err)
+			}
+			got, err := tt.mallory.Crack(context.Background(), "testdata/set5/words.txt")
+			if err != nil {
+				t.Fatalf("Crack() error = %v", err)
+			}
+			if got != c.Password {
+				t.Errorf("Crack() = %q, want %q", got, c.Password)
+			}
+		})
+	}
+}
+
+func TestCrackSimpleSRPNotFound(t *testing.T) {
+	m := NewSimpleSRPMallory()
+	c, err := NewSimpleSRPClient("alice@example.com", "not in the list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Login(m)
+	if _, err := CrackSimpleSRP(context.Background(), strings.NewReader("a\nb\nc\n"), m.A, big.NewInt(1), m.U, m.Salt, m.MAC); err != ErrNotFound {
+		t.Errorf("CrackSimpleSRP() error = %v, want %v", err, ErrNotFound)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := CrackSimpleSRP(ctx, strings.NewReader("a\nb\nc\n"), m.A, big.NewInt(1), m.U, m.Salt, m.MAC); err != context.Canceled {
+		t.Errorf("CrackSimpleSRP() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func ExampleChallenge38() {
+	m := NewSimpleSRPMallory()
+	c, err := NewSimpleSRPClient("bob@example.com", "correcthorse")
+	if err != nil {
+		fmt.Println(err)
+	}
+	ok, err := c.Login(m)
+	if err != nil {
+		fmt.Println(err)
+	}
+	pw, err := m.Crack(context.Background(), "testdata/set5/words.txt")
+	fmt.Println(ok, pw, err)
+	// output:
+	// true correcthorse <nil>
+}
+
+func TestIRoot(t *testing.T) {
+	tests := []struct {
+		x    string
+		k    int
+		want string
+	}{
+		{"0", 3, "0"},
+		{"1", 3, "1"},
+		{"26", 3, "2"},
+		{"27", 3, "3"},
+		{"28", 3, "3"},
+		{"1000000", 2, "1000"},
+		{"999999", 2, "999"},
+		{"1267650600228229401496703205376", 5, "1048576"},
+		{"1267650600228229401496703205375", 5, "1048575"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s^(1/%d)", tt.x, tt.k), func(t *testing.T) {
+			x, _ := new(big.Int).SetString(tt.x, 10)
+			if got := IRoot(x, tt.k); got.String() != tt.want {
+				t.Errorf("IRoot() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHastadBroadcast(t *testing.T) {
+	tests := []struct {
+		name       string
+		e          int
+		recipients int
+		wantErr    bool
+	}{
+		{"e=3", 3, 3, false},
+		{"e=3 extra recipients", 3, 5, false},
+		{"e=5", 5, 5, false},
+		{"too few recipients", 3, 2, true},
+	}
+	msg := []byte("this is a broadcast message")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cs, ns []*big.Int
+			for i := 0; i < tt.recipients; i++ {
+				k, err := GenerateKey(512, tt.e)
+				if err != nil {
+					t.Fatal(err)
+				}
+				cs = append(cs, k.EncryptInt(new(big.Int).SetBytes(msg)))
+				ns = append(ns, k.N)
+			}
+			got, err := HastadBroadcast(cs, ns, tt.e)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HastadBroadcast() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if string(got.Bytes()) != string(msg) {
+				t.Errorf("HastadBroadcast() = %q, want %q", got.Bytes(), msg)
+			}
+		})
+	}
+}
+
+func ExampleChallenge40() {
+	msg := new(big.Int).SetBytes([]byte("e=3 is a bad idea"))
+	var cs, ns []*big.Int
+	for i := 0; i < 3; i++ {
+		k, err := GenerateKey(1024, 3)
+		if err != nil {
+			fmt.Println(err)
+		}
+		cs = append(cs, k.EncryptInt(msg))
+		ns = append(ns, k.N)
+	}
+	m, err := HastadBroadcast(cs, ns, 3)
+	fmt.Printf("%q %v\n", m.Bytes(), err)
+	// output:
+	// "e=3 is a bad idea" <nil>
+}
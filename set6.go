@@ -0,0 +1,744 @@
+package cryptopals
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"math/big"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrReplayedCiphertext is returned when a server is asked to decrypt a ciphertext it has already decrypted.
+	ErrReplayedCiphertext = errors.New("ciphertext already decrypted")
+	// ErrInvalidSignature is returned when a signature fails verification.
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrUnsupportedHash is returned when no DigestInfo prefix is known for a hash.
+	ErrUnsupportedHash = errors.New("unsupported hash")
+)
+
+// ReplayStore remembers which ciphertext digests a server has already seen.
+type ReplayStore interface {
+	// Add records digest and reports whether it had been recorded before.
+	Add(digest []byte) (seen bool, err error)
+}
+
+// MemoryReplayStore is an in-memory ReplayStore.
+type MemoryReplayStore struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// NewMemoryReplayStore returns an empty MemoryReplayStore.
+func NewMemoryReplayStore() *MemoryReplayStore {
+	return &MemoryReplayStore{seen: make(map[string]bool)}
+}
+
+// Add satisfies the ReplayStore interface.
+func (s *MemoryReplayStore) Add(digest []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen[string(digest)] {
+		return true, nil
+	}
+	s.seen[string(digest)] = true
+	return false, nil
+}
+
+// RSADecryptionServer decrypts textbook RSA ciphertexts but refuses to decrypt the same ciphertext twice.
+type RSADecryptionServer struct {
+	key   *RSAPrivateKey
+	store ReplayStore
+}
+
+// NewRSADecryptionServer returns a server for key. If store is nil an in-memory store is used.
+func NewRSADecryptionServer(key *RSAPrivateKey, store ReplayStore) *RSADecryptionServer {
+	if store == nil {
+		store = NewMemoryReplayStore()
+	}
+	return &RSADecryptionServer{key: key, store: store}
+}
+
+// PublicKey returns the server's public key.
+func (s *RSADecryptionServer) PublicKey() *RSAPublicKey {
+	return s.key.Public()
+}
+
+// Decrypt decrypts c unless it has been submitted before.
+func (s *RSADecryptionServer) Decrypt(c []byte) ([]byte, error) {
+	ci := new(big.Int).SetBytes(c)
+	if ci.Cmp(s.key.N) >= 0 {
+		return nil, ErrMessageTooLong
+	}
+	// hash the canonical encoding so leading zeros can't be used to dodge the cache.
+	digest := sha256.Sum256(ci.FillBytes(make([]byte, s.key.Size())))
+	seen, err := s.store.Add(digest[:])
+	if err != nil {
+		return nil, errors.Wrap(err, "replay store")
+	}
+	if seen {
+		return nil, ErrReplayedCiphertext
+	}
+	return s.key.DecryptInt(ci).Bytes(), nil
+}
+
+// UnpaddedRSARecovery recovers the plaintext of c from a decryption oracle that refuses to decrypt c itself.
+// The ciphertext is blinded as c' = s**e * c, decrypted, and the result unblinded with s**-1.
+func UnpaddedRSARecovery(c []byte, pub *RSAPublicKey, decrypt func([]byte) ([]byte, error)) ([]byte, error) {
+	var s, sinv *big.Int
+	for sinv == nil {
+		var err error
+		if s, err = rand.Int(rand.Reader, new(big.Int).Sub(pub.N, big.NewInt(2))); err != nil {
+			return nil, err
+		}
+		s.Add(s, big.NewInt(2))
+		sinv, _ = InvMod(s, pub.N)
+	}
+	blinded := pub.EncryptInt(s)
+	blinded.Mul(blinded, new(big.Int).SetBytes(c)).Mod(blinded, pub.N)
+	p, err := decrypt(blinded.FillBytes(make([]byte, pub.Size())))
+	if err != nil {
+		return nil, errors.Wrap(err, "decrypting blinded ciphertext")
+	}
+	m := new(big.Int).SetBytes(p)
+	return m.Mul(m, sinv).Mod(m, pub.N).Bytes(), nil
+}
+
+// DigestInfoPrefixes holds the DER-encoded ASN.1 DigestInfo header that precedes the hash in a PKCS#1 v1.5 signature.
+var DigestInfoPrefixes = map[crypto.Hash][]byte{
+	crypto.SHA1:   {0x30, 0x21, 0x30, 0x09, 0x06, 0x05, 0x2b, 0x0e, 0x03, 0x02, 0x1a, 0x05, 0x00, 0x04, 0x14},
+	crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+}
+
+// digestInfo returns the ASN.1 DigestInfo for hashed.
+func digestInfo(hash crypto.Hash, hashed []byte) ([]byte, error) {
+	prefix, ok := DigestInfoPrefixes[hash]
+	if !ok {
+		return nil, ErrUnsupportedHash
+	}
+	if len(hashed) != hash.Size() {
+		return nil, ErrMismatchedLength
+	}
+	return append(append([]byte{}, prefix...), hashed...), nil
+}
+
+// SignPKCS1v15 signs hashed with the PKCS#1 v1.5 encoding 00 01 FF .. FF 00 ASN.1 HASH.
+func (k *RSAPrivateKey) SignPKCS1v15(hash crypto.Hash, hashed []byte) ([]byte, error) {
+	t, err := digestInfo(hash, hashed)
+	if err != nil {
+		return nil, err
+	}
+	n := k.Size()
+	if len(t)+11 > n {
+		return nil, ErrMessageTooLong
+	}
+	em := pkcs1v15SignatureBlock(t, n)
+	return k.DecryptInt(new(big.Int).SetBytes(em)).FillBytes(make([]byte, n)), nil
+}
+
+// pkcs1v15SignatureBlock returns the n-byte block 00 01 FF .. FF 00 t.
+func pkcs1v15SignatureBlock(t []byte, n int) []byte {
+	em := make([]byte, n)
+	em[1] = 0x01
+	for i := 2; i < n-len(t)-1; i++ {
+		em[i] = 0xff
+	}
+	copy(em[n-len(t):], t)
+	return em
+}
+
+// VerifyPKCS1v15 verifies a PKCS#1 v1.5 signature by re-encoding the expected block and comparing it in full.
+func VerifyPKCS1v15(pub *RSAPublicKey, hash crypto.Hash, hashed, sig []byte) error {
+	t, err := digestInfo(hash, hashed)
+	if err != nil {
+		return err
+	}
+	n := pub.Size()
+	if len(t)+11 > n {
+		return ErrMessageTooLong
+	}
+	em, err := pub.Encrypt(sig)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if !bytes.Equal(em, pkcs1v15SignatureBlock(t, n)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// VerifyPKCS1v15Sloppy verifies a PKCS#1 v1.5 signature the broken way: it parses
+// 00 01 FF .. 00 ASN.1 HASH from the left and never checks that the hash ends the block.
+func VerifyPKCS1v15Sloppy(pub *RSAPublicKey, hash crypto.Hash, hashed, sig []byte) error {
+	t, err := digestInfo(hash, hashed)
+	if err != nil {
+		return err
+	}
+	em, err := pub.Encrypt(sig)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if len(em) < 3 || em[0] != 0x00 || em[1] != 0x01 || em[2] != 0xff {
+		return ErrInvalidSignature
+	}
+	i := 2
+	for i < len(em) && em[i] == 0xff {
+		i++
+	}
+	if i == len(em) || em[i] != 0x00 {
+		return ErrInvalidSignature
+	}
+	if !bytes.HasPrefix(em[i+1:], t) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// ForgePKCS1v15Signature forges a signature over hashed that VerifyPKCS1v15Sloppy accepts for a
+// small public exponent. It builds 00 01 FF 00 ASN.1 HASH followed by garbage and takes the integer
+// e-th root, relying on the garbage bytes to absorb the rounding error.
+func ForgePKCS1v15Signature(pub *RSAPublicKey, hash crypto.Hash, hashed []byte) ([]byte, error) {
+	t, err := digestInfo(hash, hashed)
+	if err != nil {
+		return nil, err
+	}
+	n := pub.Size()
+	prefix := append([]byte{0x00, 0x01, 0xff, 0x00}, t...)
+	if len(prefix) > n {
+		return nil, ErrMessageTooLong
+	}
+	lo := make([]byte, n)
+	copy(lo, prefix)
+	hi := bytes.Repeat([]byte{0xff}, n)
+	copy(hi, prefix)
+	loInt, hiInt := new(big.Int).SetBytes(lo), new(big.Int).SetBytes(hi)
+
+	// smallest s with s**e >= lo
+	e := int(pub.E.Int64())
+	s := IRoot(loInt, e)
+	if new(big.Int).Exp(s, pub.E, nil).Cmp(loInt) < 0 {
+		s.Add(s, bigOne)
+	}
+	if new(big.Int).Exp(s, pub.E, nil).Cmp(hiInt) > 0 {
+		return nil, errors.Wrap(ErrNotFound, "not enough garbage bytes to absorb the root")
+	}
+	return s.FillBytes(make([]byte, n)), nil
+}
+
+// DSAKeyFingerprint returns the hex-encoded SHA-1 of the hex encoding of x, the form in which the challenges publish private keys.
+func DSAKeyFingerprint(x *big.Int) string {
+	h := sha1.Sum([]byte(x.Text(16)))
+	return hex.EncodeToString(h[:])
+}
+
+// RecoverDSAKeyFromNonce recovers the private key behind a signature whose nonce lies in [lo, hi).
+// A candidate x is accepted if DSAKeyFingerprint(x) equals fingerprint or, when fingerprint is empty, if g**x = y.
+// Candidate nonces are tried in parallel; the search stops at the first accepted key or when ctx is cancelled.
+func RecoverDSAKeyFromNonce(ctx context.Context, pub *DSAPublicKey, hashed []byte, r, s *big.Int, lo, hi uint64, fingerprint string) (*DSAPrivateKey, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := uint64(runtime.NumCPU())
+	found := make(chan *DSAPrivateKey, 1)
+	var wg sync.WaitGroup
+	for w := uint64(0); w < workers; w++ {
+		wg.Add(1)
+		go func(start uint64) {
+			defer wg.Done()
+			k, gk := new(big.Int), new(big.Int)
+			for n, i := 0, start; i < hi; n, i = n+1, i+workers {
+				if n%256 == 0 && ctx.Err() != nil {
+					return
+				}
+				// r = (g**k mod p) mod q is cheap to check for a small k.
+				k.SetUint64(i)
+				gk.Exp(pub.G, k, pub.P)
+				if gk.Mod(gk, pub.Q).Cmp(r) != 0 {
+					continue
+				}
+				var priv *DSAPrivateKey
+				if fingerprint == "" {
+					var err error
+					if priv, err = DSAPrivateKeyFromNonce(pub, hashed, r, s, k); err != nil {
+						continue
+					}
+				} else {
+					x, err := dsaXFromNonce(&pub.DSAParameters, hashed, r, s, k)
+					if err != nil || DSAKeyFingerprint(x) != fingerprint {
+						continue
+					}
+					priv = &DSAPrivateKey{DSAPublicKey: *pub, X: x}
+				}
+				select {
+				case found <- priv:
+				default:
+				}
+				cancel()
+				return
+			}
+		}(lo + w)
+	}
+	wg.Wait()
+
+	select {
+	case priv := <-found:
+		return priv, nil
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, ErrNotFound
+}
+
+// DSASignedMessage is one record of a signed-message corpus in the challenge 44 format.
+type DSASignedMessage struct {
+	Msg  string
+	S, R *big.Int
+	M    *big.Int // SHA-1 of Msg
+}
+
+// ParseDSASignedMessages parses records of the form
+//
+//	msg: <message>
+//	s: <decimal>
+//	r: <decimal>
+//	m: <hex>
+//
+// Lines may end in CRLF, and blank lines between records are ignored. Malformed input, including an m
+// that is not SHA-1(msg), is reported with its line number.
+func ParseDSASignedMessages(r io.Reader) ([]DSASignedMessage, error) {
+	fields := []string{"msg", "s", "r", "m"}
+	var (
+		results []DSASignedMessage
+		cur     DSASignedMessage
+		field   int
+		lineNo  int
+	)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSuffix(sc.Text(), "\r")
+		if field == 0 && strings.TrimSpace(line) == "" {
+			continue
+		}
+		want := fields[field] + ": "
+		if !strings.HasPrefix(line, want) {
+			return nil, errors.Errorf("line %d: expected %q field", lineNo, fields[field])
+		}
+		value := strings.TrimPrefix(line, want)
+		var ok bool
+		switch fields[field] {
+		case "msg":
+			cur.Msg, ok = value, true
+		case "s":
+			cur.S, ok = new(big.Int).SetString(strings.TrimSpace(value), 10)
+		case "r":
+			cur.R, ok = new(big.Int).SetString(strings.TrimSpace(value), 10)
+		case "m":
+			cur.M, ok = new(big.Int).SetString(strings.TrimSpace(value), 16)
+		}
+		if !ok {
+			return nil, errors.Errorf("line %d: invalid %q value %q", lineNo, fields[field], value)
+		}
+		field = (field + 1) % len(fields)
+		if field == 0 {
+			h := sha1.Sum([]byte(cur.Msg))
+			if cur.M.Cmp(new(big.Int).SetBytes(h[:])) != 0 {
+				return nil, errors.Errorf("line %d: m is not the SHA-1 of msg", lineNo)
+			}
+			results = append(results, cur)
+			cur = DSASignedMessage{}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if field != 0 {
+		return nil, errors.Errorf("line %d: incomplete record, missing %q field", lineNo, fields[field])
+	}
+	return results, nil
+}
+
+// FindRepeatedNonces groups signatures by r and returns the groups with more than one member.
+// Signatures that share r were made with the same nonce.
+func FindRepeatedNonces(msgs []DSASignedMessage) [][]DSASignedMessage {
+	var order []string
+	groups := make(map[string][]DSASignedMessage)
+	for _, m := range msgs {
+		key := m.R.String()
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], m)
+	}
+	var results [][]DSASignedMessage
+	for _, key := range order {
+		if len(groups[key]) > 1 {
+			results = append(results, groups[key])
+		}
+	}
+	return results
+}
+
+// DSANonceFromRepeatedNonce recovers the shared nonce of two signatures: k = (m1 - m2) / (s1 - s2) mod q.
+func DSANonceFromRepeatedNonce(q *big.Int, a, b DSASignedMessage) (*big.Int, error) {
+	ds := new(big.Int).Sub(a.S, b.S)
+	dsinv, err := InvMod(ds, q)
+	if err != nil {
+		return nil, err
+	}
+	k := new(big.Int).Sub(a.M, b.M)
+	return k.Mul(k, dsinv).Mod(k, q), nil
+}
+
+// RecoverDSAKeyFromRepeatedNonces finds signatures in msgs that reuse a nonce and recovers the private key for pub from them.
+func RecoverDSAKeyFromRepeatedNonces(pub *DSAPublicKey, msgs []DSASignedMessage) (*DSAPrivateKey, error) {
+	for _, group := range FindRepeatedNonces(msgs) {
+		for i := 0; i < len(group); i++ {
+			for j := i + 1; j < len(group); j++ {
+				k, err := DSANonceFromRepeatedNonce(pub.Q, group[i], group[j])
+				if err != nil {
+					continue
+				}
+				priv, err := DSAPrivateKeyFromNonce(pub, group[i].M.Bytes(), group[i].R, group[i].S, k)
+				if err == nil {
+					return priv, nil
+				}
+			}
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// DSAMagicSignature returns a signature that verifies for any message under a public key whose generator is p+1.
+// With g = 1 mod p, verification reduces to comparing (y**(r/s) mod p) mod q with r, so for any z,
+// r = (y**z mod p) mod q and s = r/z mod q is accepted.
+func DSAMagicSignature(pub *DSAPublicKey, z *big.Int) (r, s *big.Int, err error) {
+	zinv, err := InvMod(z, pub.Q)
+	if err != nil {
+		return nil, nil, err
+	}
+	r = new(big.Int).Exp(pub.Y, z, pub.P)
+	r.Mod(r, pub.Q)
+	s = new(big.Int).Mul(r, zinv)
+	s.Mod(s, pub.Q)
+	return r, s, nil
+}
+
+// RSAParityOracle reports whether the plaintext of a ciphertext is even.
+type RSAParityOracle interface {
+	Even(c *big.Int) (bool, error)
+}
+
+// RSAParityServer is an RSAParityOracle backed by a private key.
+type RSAParityServer struct {
+	key *RSAPrivateKey
+}
+
+// NewRSAParityServer returns a parity oracle for key.
+func NewRSAParityServer(key *RSAPrivateKey) *RSAParityServer {
+	return &RSAParityServer{key: key}
+}
+
+// PublicKey returns the server's public key.
+func (s *RSAParityServer) PublicKey() *RSAPublicKey {
+	return s.key.Public()
+}
+
+// Even satisfies the RSAParityOracle interface.
+func (s *RSAParityServer) Even(c *big.Int) (bool, error) {
+	if c.Cmp(s.key.N) >= 0 {
+		return false, ErrMessageTooLong
+	}
+	return s.key.DecryptInt(c).Bit(0) == 0, nil
+}
+
+// ParityOracleDecrypt recovers the plaintext of c using a parity oracle.
+//
+// Multiplying c by 2**e doubles the plaintext; since n is odd, 2m mod n is even exactly when m < n/2.
+// After i queries the plaintext is known to lie in [a n / 2**i, (a+1) n / 2**i), and the bounds are kept
+// as the exact integer a so that no precision is lost on the final byte. If progress is non-nil it is
+// called after every query with the current upper bound.
+func ParityOracleDecrypt(pub *RSAPublicKey, c *big.Int, oracle RSAParityOracle, progress func(upper *big.Int)) (*big.Int, error) {
+	double := pub.EncryptInt(big.NewInt(2))
+	cur := new(big.Int).Set(c)
+	a := new(big.Int)
+	k := pub.N.BitLen()
+	for i := 1; i <= k; i++ {
+		cur.Mul(cur, double).Mod(cur, pub.N)
+		even, err := oracle.Even(cur)
+		if err != nil {
+			return nil, errors.Wrapf(err, "query %d", i)
+		}
+		a.Lsh(a, 1)
+		if !even {
+			a.Add(a, bigOne)
+		}
+		if progress != nil {
+			upper := new(big.Int).Add(a, bigOne)
+			upper.Mul(upper, pub.N).Rsh(upper, uint(i))
+			progress(upper)
+		}
+	}
+	// the interval is now narrower than 1, so m is the smallest integer >= a n / 2**k.
+	m := new(big.Int).Mul(a, pub.N)
+	rem := new(big.Int)
+	m.QuoRem(m, new(big.Int).Lsh(bigOne, uint(k)), rem)
+	if rem.Sign() != 0 {
+		m.Add(m, bigOne)
+	}
+	return m, nil
+}
+
+// PadPKCS1v15Encryption pads msg for encryption under a k-byte modulus as 00 02 PS 00 M, where PS is at least 8 random non-zero bytes.
+func PadPKCS1v15Encryption(msg []byte, k int) ([]byte, error) {
+	if len(msg) > k-11 {
+		return nil, ErrMessageTooLong
+	}
+	em := make([]byte, k)
+	em[1] = 0x02
+	ps := em[2 : k-len(msg)-1]
+	if _, err := io.ReadFull(rand.Reader, ps); err != nil {
+		return nil, err
+	}
+	for i := range ps {
+		for ps[i] == 0 {
+			if _, err := io.ReadFull(rand.Reader, ps[i:i+1]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	copy(em[k-len(msg):], msg)
+	return em, nil
+}
+
+// UnpadPKCS1v15Encryption removes PKCS#1 v1.5 encryption padding.
+func UnpadPKCS1v15Encryption(em []byte) ([]byte, error) {
+	if len(em) < 11 || em[0] != 0x00 || em[1] != 0x02 {
+		return nil, ErrInvalidPadding
+	}
+	i := bytes.IndexByte(em[2:], 0x00)
+	if i < 8 {
+		return nil, ErrInvalidPadding
+	}
+	return em[2+i+1:], nil
+}
+
+// EncryptPKCS1v15 pads msg with PKCS#1 v1.5 encryption padding and encrypts it.
+func (k *RSAPublicKey) EncryptPKCS1v15(msg []byte) ([]byte, error) {
+	em, err := PadPKCS1v15Encryption(msg, k.Size())
+	if err != nil {
+		return nil, err
+	}
+	return k.Encrypt(em)
+}
+
+// PKCS1v15PaddingOracle reports whether the plaintext of a ciphertext starts with 00 02.
+type PKCS1v15PaddingOracle interface {
+	Conforming(c *big.Int) (bool, error)
+}
+
+// RSAPaddingServer is a PKCS1v15PaddingOracle backed by a private key.
+type RSAPaddingServer struct {
+	key *RSAPrivateKey
+}
+
+// NewRSAPaddingServer returns a padding oracle for key.
+func NewRSAPaddingServer(key *RSAPrivateKey) *RSAPaddingServer {
+	return &RSAPaddingServer{key: key}
+}
+
+// PublicKey returns the server's public key.
+func (s *RSAPaddingServer) PublicKey() *RSAPublicKey {
+	return s.key.Public()
+}
+
+// Conforming satisfies the PKCS1v15PaddingOracle interface.
+func (s *RSAPaddingServer) Conforming(c *big.Int) (bool, error) {
+	if c.Cmp(s.key.N) >= 0 {
+		return false, ErrMessageTooLong
+	}
+	em := s.key.DecryptInt(c).FillBytes(make([]byte, s.key.Size()))
+	return em[0] == 0x00 && em[1] == 0x02, nil
+}
+
+// interval is a closed range of candidate plaintexts.
+type interval struct {
+	a, b *big.Int
+}
+
+// ceilDiv returns ceil(x / y) for positive y.
+func ceilDiv(x, y *big.Int) *big.Int {
+	q, m := new(big.Int).DivMod(x, y, new(big.Int))
+	if m.Sign() != 0 {
+		q.Add(q, bigOne)
+	}
+	return q
+}
+
+// floorDiv returns floor(x / y) for positive y.
+func floorDiv(x, y *big.Int) *big.Int {
+	return new(big.Int).Div(x, y)
+}
+
+// Bleichenbacher98 recovers the plaintext of c from a PKCS#1 v1.5 padding oracle using Bleichenbacher's
+// adaptive chosen-ciphertext attack. It returns the padded plaintext and the number of oracle queries made.
+func Bleichenbacher98(ctx context.Context, pub *RSAPublicKey, c *big.Int, oracle PKCS1v15PaddingOracle) (*big.Int, int, error) {
+	n := pub.N
+	k := pub.Size()
+	B := new(big.Int).Lsh(bigOne, uint(8*(k-2)))
+	B2 := new(big.Int).Lsh(B, 1)
+	B3 := new(big.Int).Add(B2, B)
+	B3m1 := new(big.Int).Sub(B3, bigOne)
+
+	queries := 0
+	query := func(c0, s *big.Int) (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		queries++
+		x := pub.EncryptInt(s)
+		x.Mul(x, c0).Mod(x, n)
+		return oracle.Conforming(x)
+	}
+
+	// Step 1: blinding. A ciphertext that is already conforming needs s0 = 1.
+	s0 := big.NewInt(1)
+	for {
+		ok, err := query(c, s0)
+		if err != nil {
+			return nil, queries, err
+		}
+		if ok {
+			break
+		}
+		if s0, err = randRange(big.NewInt(2), n); err != nil {
+			return nil, queries, err
+		}
+	}
+	c0 := pub.EncryptInt(s0)
+	c0.Mul(c0, c).Mod(c0, n)
+	M := []interval{{new(big.Int).Set(B2), new(big.Int).Set(B3m1)}}
+	var s *big.Int
+
+	for i := 1; ; i++ {
+		switch {
+		case i == 1:
+			// Step 2a: the smallest s >= n/3B that yields a conforming plaintext.
+			s = ceilDiv(n, B3)
+			for {
+				ok, err := query(c0, s)
+				if err != nil {
+					return nil, queries, err
+				}
+				if ok {
+					break
+				}
+				s.Add(s, bigOne)
+			}
+		case len(M) > 1:
+			// Step 2b: search with more than one interval left.
+			s = new(big.Int).Add(s, bigOne)
+			for {
+				ok, err := query(c0, s)
+				if err != nil {
+					return nil, queries, err
+				}
+				if ok {
+					break
+				}
+				s.Add(s, bigOne)
+			}
+		default:
+			// Step 2c: search with one interval left, roughly halving it each round.
+			a, b := M[0].a, M[0].b
+			r := new(big.Int).Mul(b, s)
+			r.Sub(r, B2).Lsh(r, 1)
+			r = ceilDiv(r, n)
+		search:
+			for ; ; r.Add(r, bigOne) {
+				rn := new(big.Int).Mul(r, n)
+				lo := ceilDiv(new(big.Int).Add(B2, rn), b)
+				hi := floorDiv(new(big.Int).Add(B3m1, rn), a)
+				for si := lo; si.Cmp(hi) <= 0; si.Add(si, bigOne) {
+					ok, err := query(c0, si)
+					if err != nil {
+						return nil, queries, err
+					}
+					if ok {
+						s = si
+						break search
+					}
+				}
+			}
+		}
+
+		// Step 3: narrow the set of solutions.
+		var next []interval
+		for _, iv := range M {
+			rlo := new(big.Int).Mul(iv.a, s)
+			rlo = ceilDiv(rlo.Sub(rlo, B3m1), n)
+			rhi := new(big.Int).Mul(iv.b, s)
+			rhi = floorDiv(rhi.Sub(rhi, B2), n)
+			for r := rlo; r.Cmp(rhi) <= 0; r = new(big.Int).Add(r, bigOne) {
+				rn := new(big.Int).Mul(r, n)
+				a := ceilDiv(new(big.Int).Add(B2, rn), s)
+				if a.Cmp(iv.a) < 0 {
+					a = iv.a
+				}
+				b := floorDiv(new(big.Int).Add(B3m1, rn), s)
+				if b.Cmp(iv.b) > 0 {
+					b = iv.b
+				}
+				if a.Cmp(b) > 0 {
+					continue
+				}
+				next = mergeInterval(next, interval{a, b})
+			}
+		}
+		if len(next) == 0 {
+			return nil, queries, errors.New("no intervals left")
+		}
+		M = next
+
+		// Step 4: done once a single value remains.
+		if len(M) == 1 && M[0].a.Cmp(M[0].b) == 0 {
+			s0inv, err := InvMod(s0, n)
+			if err != nil {
+				return nil, queries, err
+			}
+			m := new(big.Int).Mul(M[0].a, s0inv)
+			return m.Mod(m, n), queries, nil
+		}
+	}
+}
+
+// mergeInterval adds iv to a set of disjoint intervals, merging any that overlap.
+func mergeInterval(set []interval, iv interval) []interval {
+	a, b := iv.a, iv.b
+	var result []interval
+	for _, o := range set {
+		if o.b.Cmp(a) < 0 || o.a.Cmp(b) > 0 {
+			result = append(result, o)
+			continue
+		}
+		if o.a.Cmp(a) < 0 {
+			a = o.a
+		}
+		if o.b.Cmp(b) > 0 {
+			b = o.b
+		}
+	}
+	return append(result, interval{a, b})
+}
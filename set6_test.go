@@ -0,0 +1,542 @@
+package cryptopals
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	_ "crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRSADecryptionServer(t *testing.T) {
+	k, err := GenerateKey(512, 65537)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewRSADecryptionServer(k, nil)
+	c, err := srv.PublicKey().Encrypt([]byte(`{"time": 1356304276, "social": "555-55-5555"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := srv.Decrypt(c); err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if _, err := srv.Decrypt(c); err != ErrReplayedCiphertext {
+		t.Errorf("Decrypt() error = %v, want %v", err, ErrReplayedCiphertext)
+	}
+	if _, err := srv.Decrypt(append([]byte{0, 0}, c...)); err != ErrReplayedCiphertext {
+		t.Errorf("Decrypt() with leading zeros error = %v, want %v", err, ErrReplayedCiphertext)
+	}
+}
+
+func ExampleChallenge41() {
+	k, err := GenerateKey(1024, 65537)
+	if err != nil {
+		fmt.Println(err)
+	}
+	srv := NewRSADecryptionServer(k, nil)
+	c, err := srv.PublicKey().Encrypt([]byte(`{"time": 1356304276, "social": "555-55-5555"}`))
+	if err != nil {
+		fmt.Println(err)
+	}
+	// the victim's request
+	if _, err := srv.Decrypt(c); err != nil {
+		fmt.Println(err)
+	}
+	p, err := UnpaddedRSARecovery(c, srv.PublicKey(), srv.Decrypt)
+	fmt.Printf("%s %v\n", p, err)
+	// output:
+	// {"time": 1356304276, "social": "555-55-5555"} <nil>
+}
+
+func TestVerifyPKCS1v15(t *testing.T) {
+	k, err := GenerateKey(1024, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("hi mom")
+	tests := []struct {
+		name string
+		hash crypto.Hash
+	}{
+		{"sha1", crypto.SHA1},
+		{"sha256", crypto.SHA256},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := tt.hash.New()
+			h.Write(msg)
+			hashed := h.Sum(nil)
+			sig, err := k.SignPKCS1v15(tt.hash, hashed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := VerifyPKCS1v15(k.Public(), tt.hash, hashed, sig); err != nil {
+				t.Errorf("VerifyPKCS1v15() error = %v", err)
+			}
+			if err := VerifyPKCS1v15Sloppy(k.Public(), tt.hash, hashed, sig); err != nil {
+				t.Errorf("VerifyPKCS1v15Sloppy() error = %v", err)
+			}
+			// interop with crypto/rsa
+			if err := rsa.VerifyPKCS1v15(&rsa.PublicKey{N: k.N, E: 3}, tt.hash, hashed, sig); err != nil {
+				t.Errorf("rsa.VerifyPKCS1v15() error = %v", err)
+			}
+			hashed[0] ^= 1
+			if err := VerifyPKCS1v15(k.Public(), tt.hash, hashed, sig); err != ErrInvalidSignature {
+				t.Errorf("VerifyPKCS1v15() error = %v, want %v", err, ErrInvalidSignature)
+			}
+			if err := VerifyPKCS1v15Sloppy(k.Public(), tt.hash, hashed, sig); err != ErrInvalidSignature {
+				t.Errorf("VerifyPKCS1v15Sloppy() error = %v, want %v", err, ErrInvalidSignature)
+			}
+		})
+	}
+}
+
+func TestForgePKCS1v15Signature(t *testing.T) {
+	tests := []struct {
+		name    string
+		bits    int
+		hash    crypto.Hash
+		wantErr bool
+	}{
+		{"1024 sha1", 1024, crypto.SHA1, false},
+		{"2048 sha256", 2048, crypto.SHA256, false},
+		{"1024 sha256 too short", 1024, crypto.SHA256, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := GenerateKey(tt.bits, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := tt.hash.New()
+			h.Write([]byte("hi mom"))
+			hashed := h.Sum(nil)
+			sig, err := ForgePKCS1v15Signature(k.Public(), tt.hash, hashed)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ForgePKCS1v15Signature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if err := VerifyPKCS1v15Sloppy(k.Public(), tt.hash, hashed, sig); err != nil {
+				t.Errorf("VerifyPKCS1v15Sloppy() error = %v", err)
+			}
+			if err := VerifyPKCS1v15(k.Public(), tt.hash, hashed, sig); err != ErrInvalidSignature {
+				t.Errorf("VerifyPKCS1v15() error = %v, want %v", err, ErrInvalidSignature)
+			}
+		})
+	}
+}
+
+func ExampleChallenge42() {
+	k, err := GenerateKey(1024, 3)
+	if err != nil {
+		fmt.Println(err)
+	}
+	hashed := sha1.Sum([]byte("hi mom"))
+	sig, err := ForgePKCS1v15Signature(k.Public(), crypto.SHA1, hashed[:])
+	if err != nil {
+		fmt.Println(err)
+	}
+	fmt.Println(VerifyPKCS1v15Sloppy(k.Public(), crypto.SHA1, hashed[:], sig))
+	fmt.Println(VerifyPKCS1v15(k.Public(), crypto.SHA1, hashed[:], sig))
+	// output:
+	// <nil>
+	// invalid signature
+}
+
+func TestRecoverDSAKeyFromNonce(t *testing.T) {
+	k, err := GenerateDSAKey(ChallengeDSAParameters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed := sha1.Sum([]byte("small nonces are a bad idea"))
+	r, s, err := k.SignWithNonce(hashed[:], big.NewInt(40000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fingerprint := range []string{"", DSAKeyFingerprint(k.X)} {
+		got, err := RecoverDSAKeyFromNonce(context.Background(), &k.DSAPublicKey, hashed[:], r, s, 0, 1<<16, fingerprint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.X.Cmp(k.X) != 0 {
+			t.Errorf("RecoverDSAKeyFromNonce(fingerprint %q) = %v, want %v", fingerprint, got.X, k.X)
+		}
+	}
+	if _, err := RecoverDSAKeyFromNonce(context.Background(), &k.DSAPublicKey, hashed[:], r, s, 0, 1<<16, DSAKeyFingerprint(new(big.Int).Add(k.X, bigOne))); err != ErrNotFound {
+		t.Errorf("RecoverDSAKeyFromNonce() with a wrong fingerprint: error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := RecoverDSAKeyFromNonce(context.Background(), &k.DSAPublicKey, hashed[:], r, s, 0, 1000, ""); err != ErrNotFound {
+		t.Errorf("RecoverDSAKeyFromNonce() error = %v, want %v", err, ErrNotFound)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := RecoverDSAKeyFromNonce(ctx, &k.DSAPublicKey, hashed[:], r, s, 0, 1<<16, ""); err != context.Canceled {
+		t.Errorf("RecoverDSAKeyFromNonce() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func ExampleChallenge43() {
+	pub := &DSAPublicKey{
+		DSAParameters: ChallengeDSAParameters,
+		Y:             mustInt("84ad4719d044495496a3201c8ff484feb45b962e7302e56a392aee4abab3e4bdebf2955b4736012f21a08084056b19bcd7fee56048e004e44984e2f411788efdc837a0d2e5abb7b555039fd243ac01f0fb2ed1dec568280ce678e931868d23eb095fde9d3779191b8c0299d6e07bbb283e6633451e535c45513b2d33c99ea17", 16),
+	}
+	msg := "For those that envy a MC it can be hazardous to your health\nSo be friendly, a matter of life and death, just like a etch-a-sketch\n"
+	hashed := sha1.Sum([]byte(msg))
+	r, _ := new(big.Int).SetString("548099063082341131477253921760299949438196259240", 10)
+	s, _ := new(big.Int).SetString("857042759984254168557880549501802188789837994940", 10)
+
+	priv, err := RecoverDSAKeyFromNonce(context.Background(), pub, hashed[:], r, s, 0, 1<<16, "0954edd5e0afe5542a4adf012611a91912a3ec16")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("%x\n", hashed)
+	fmt.Println(DSAKeyFingerprint(priv.X))
+	// output:
+	// d2d0714f014a9784047eaeccf956520045c45265
+	// 0954edd5e0afe5542a4adf012611a91912a3ec16
+}
+
+func TestParseDSASignedMessages(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    int
+		wantErr string
+	}{
+		{"empty", "", 0, ""},
+		{"two records", "msg: hello \ns: 1\nr: 2\nm: c4d871ad13ad00fde9a7bb7ff7ed2543aec54241\nmsg: world\ns: 3\nr: 4\nm: 7c211433f02071597741e6ff5a8ea34789abbf43\n", 2, ""},
+		{"blank lines", "\nmsg: hello\ns: 1\nr: 2\nm: aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d\n\n\nmsg: world\ns: 3\nr: 4\nm: 7c211433f02071597741e6ff5a8ea34789abbf43\n", 2, ""},
+		{"crlf", "msg: hello\r\ns: 1\r\nr: 2\r\nm: aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d\r\n", 1, ""},
+		{"bad s", "msg: hello\ns: x1\nr: 2\nm: ff\n", 0, "line 2: "},
+		{"out of order", "msg: hello\ns: 1\nm: ff\nr: 2\n", 0, "line 3: "},
+		{"bad m", "msg: a\ns: 1\nr: 2\nm: 86f7e437faa5a7fce15d1ddcb9eaeaea377667b8\nmsg: b\ns: 1\nr: 2\nm: zz\n", 0, "line 8: "},
+		{"wrong m", "msg: a\ns: 1\nr: 2\nm: 86f7e437faa5a7fce15d1ddcb9eaeaea377667b8\nmsg: b\ns: 1\nr: 2\nm: 86f7e437faa5a7fce15d1ddcb9eaeaea377667b8\n", 0, "line 8: m is not"},
+		{"truncated", "msg: hello\ns: 1\n", 0, "line 2: incomplete"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDSASignedMessages(strings.NewReader(tt.in))
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Errorf("ParseDSASignedMessages() error = %v, want prefix %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDSASignedMessages() error = %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("ParseDSASignedMessages() returned %d records, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestRecoverDSAKeyFromRepeatedNonces(t *testing.T) {
+	k, err := GenerateDSAKey(ChallengeDSAParameters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reused, err := randRange(bigOne, k.Q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var corpus strings.Builder
+	for i, msg := range []string{"Listen for me, you better listen for me now. ", "Pure black people mon is all I mon know. ", "Yeah me shoes a an tear up an' now me toes is a show a ", "When me rockin' the microphone me rock on steady, "} {
+		hashed := sha1.Sum([]byte(msg))
+		var r, s *big.Int
+		if i%2 == 0 {
+			r, s, err = k.SignWithNonce(hashed[:], reused)
+		} else {
+			r, s, err = k.Sign(hashed[:])
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&corpus, "msg: %s\ns: %s\nr: %s\nm: %x\n", msg, s, r, hashed)
+	}
+	msgs, err := ParseDSASignedMessages(strings.NewReader(corpus.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groups := FindRepeatedNonces(msgs); len(groups) != 1 || len(groups[0]) != 2 {
+		t.Errorf("FindRepeatedNonces() = %v, want one pair", groups)
+	}
+	got, err := RecoverDSAKeyFromRepeatedNonces(&k.DSAPublicKey, msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.X.Cmp(k.X) != 0 {
+		t.Errorf("RecoverDSAKeyFromRepeatedNonces() = %v, want %v", got.X, k.X)
+	}
+	if _, err := RecoverDSAKeyFromRepeatedNonces(&k.DSAPublicKey, msgs[1:2]); err != ErrNotFound {
+		t.Errorf("RecoverDSAKeyFromRepeatedNonces() error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestChallenge44(t *testing.T) {
+	f, err := os.Open("testdata/set6/44.txt")
+	if os.IsNotExist(err) {
+		t.Skip("testdata/set6/44.txt is not present; fetch it from https://cryptopals.com/static/challenge-data/44.txt")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	msgs, err := ParseDSASignedMessages(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := &DSAPublicKey{
+		DSAParameters: ChallengeDSAParameters,
+		Y:             mustInt("2d026f4bf30195ede3a088da85e398ef869611d0f68f0713d51c9c1a3a26c95105d915e2d8cdf26d056b86b8a7b85519b1c23cc3ecdc6062650462e3063bd179c2a6581519f674a61f1d89a1fff27171ebc1b93d4dc57bceb7ae2430f98a6a4d83d8279ee65d71c1203d2c96d65ebbf7cce9d32971c3de5084cce04a2e147821", 16),
+	}
+	priv, err := RecoverDSAKeyFromRepeatedNonces(pub, msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := DSAKeyFingerprint(priv.X), "ca8f6f7c66fa362d40760d135b763eb8527d3d52"; got != want {
+		t.Errorf("DSAKeyFingerprint() = %v, want %v", got, want)
+	}
+}
+
+func TestDSAParameterTampering(t *testing.T) {
+	p, q := ChallengeDSAParameters.P, ChallengeDSAParameters.Q
+	tests := []struct {
+		name string
+		g    *big.Int
+	}{
+		{"g=0", big.NewInt(0)},
+		{"g=p+1", new(big.Int).Add(p, bigOne)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := GenerateDSAKey(DSAParameters{P: p, Q: q, G: tt.g})
+			if err != nil {
+				t.Fatal(err)
+			}
+			hello := sha1.Sum([]byte("Hello, world"))
+			if _, _, err := k.Sign(hello[:]); err != ErrInvalidDSAParameters {
+				t.Errorf("Sign() error = %v, want %v", err, ErrInvalidDSAParameters)
+			}
+			var r, s *big.Int
+			if tt.g.Sign() == 0 {
+				// every signature made with g = 0 has r = 0.
+				if r, s, err = k.SignWithNonce(hello[:], big.NewInt(42)); err != nil {
+					t.Fatal(err)
+				}
+				if r.Sign() != 0 {
+					t.Fatalf("SignWithNonce() r = %v, want 0", r)
+				}
+			} else {
+				if r, s, err = DSAMagicSignature(&k.DSAPublicKey, big.NewInt(42)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, msg := range []string{"Hello, world", "Goodbye, world"} {
+				hashed := sha1.Sum([]byte(msg))
+				if !VerifyDSANaive(&k.DSAPublicKey, hashed[:], r, s) {
+					t.Errorf("VerifyDSANaive(%q) = false, want true", msg)
+				}
+				if VerifyDSA(&k.DSAPublicKey, hashed[:], r, s) {
+					t.Errorf("VerifyDSA(%q) = true, want false", msg)
+				}
+			}
+		})
+	}
+}
+
+func ExampleChallenge45() {
+	params := ChallengeDSAParameters
+	params.G = new(big.Int).Add(params.P, bigOne)
+	k, err := GenerateDSAKey(params)
+	if err != nil {
+		fmt.Println(err)
+	}
+	r, s, err := DSAMagicSignature(&k.DSAPublicKey, big.NewInt(7))
+	if err != nil {
+		fmt.Println(err)
+	}
+	for _, msg := range []string{"Hello, world", "Goodbye, world"} {
+		hashed := sha1.Sum([]byte(msg))
+		fmt.Println(msg, VerifyDSANaive(&k.DSAPublicKey, hashed[:], r, s))
+	}
+	// output:
+	// Hello, world true
+	// Goodbye, world true
+}
+
+func TestParityOracleDecrypt(t *testing.T) {
+	tests := []struct {
+		name string
+		bits int
+		msg  []byte
+	}{
+		{"short", 512, []byte("A")},
+		{"trailing zero byte", 512, []byte("ends in zero\x00")},
+		{"trailing ff byte", 1024, []byte("ends in ff\xff")},
+		{"zero", 256, []byte{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := GenerateKey(tt.bits, 65537)
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := NewRSAParityServer(k)
+			m := new(big.Int).SetBytes(tt.msg)
+			var queries int
+			got, err := ParityOracleDecrypt(srv.PublicKey(), srv.PublicKey().EncryptInt(m), srv, func(*big.Int) { queries++ })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Cmp(m) != 0 {
+				t.Errorf("ParityOracleDecrypt() = %x, want %x", got, m)
+			}
+			if queries != k.N.BitLen() {
+				t.Errorf("progress called %d times, want %d", queries, k.N.BitLen())
+			}
+		})
+	}
+}
+
+func ExampleChallenge46() {
+	k, err := GenerateKey(1024, 65537)
+	if err != nil {
+		fmt.Println(err)
+	}
+	srv := NewRSAParityServer(k)
+	secret, _ := base64.StdEncoding.DecodeString("VGhhdCdzIHdoeSBJIGZvdW5kIHlvdSBkb24ndCBwbGF5IGFyb3VuZCB3aXRoIHRoZSBGdW5reSBDb2xkIE1lZGluYQ==")
+	c := srv.PublicKey().EncryptInt(new(big.Int).SetBytes(secret))
+
+	m, err := ParityOracleDecrypt(srv.PublicKey(), c, srv, func(upper *big.Int) {
+		// a CLI would print upper.Bytes() here for the hollywood-style display.
+	})
+	fmt.Printf("%q %v\n", m.Bytes(), err)
+	// output:
+	// "That's why I found you don't play around with the Funky Cold Medina" <nil>
+}
+
+func TestPKCS1v15EncryptionPadding(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     []byte
+		k       int
+		wantErr bool
+	}{
+		{"empty", []byte{}, 32, false},
+		{"fits", []byte("kick it, CC"), 32, false},
+		{"exactly fits", bytes.Repeat([]byte{'a'}, 21), 32, false},
+		{"too long", bytes.Repeat([]byte{'a'}, 22), 32, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			em, err := PadPKCS1v15Encryption(tt.msg, tt.k)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PadPKCS1v15Encryption() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(em) != tt.k {
+				t.Errorf("len(PadPKCS1v15Encryption()) = %d, want %d", len(em), tt.k)
+			}
+			got, err := UnpadPKCS1v15Encryption(em)
+			if err != nil {
+				t.Fatalf("UnpadPKCS1v15Encryption() error = %v", err)
+			}
+			if !bytes.Equal(got, tt.msg) {
+				t.Errorf("UnpadPKCS1v15Encryption() = %q, want %q", got, tt.msg)
+			}
+		})
+	}
+}
+
+func TestBleichenbacher98(t *testing.T) {
+	tests := []struct {
+		name string
+		bits int
+		msg  string
+		slow bool
+	}{
+		{"256", 256, "kick it, CC", false},
+		{"768", 768, "kick it, CC", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.slow && testing.Short() {
+				t.Skip("skipping 768-bit Bleichenbacher attack in short mode")
+			}
+			k, err := GenerateKey(tt.bits, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := NewRSAPaddingServer(k)
+			c, err := srv.PublicKey().EncryptPKCS1v15([]byte(tt.msg))
+			if err != nil {
+				t.Fatal(err)
+			}
+			m, queries, err := Bleichenbacher98(context.Background(), srv.PublicKey(), new(big.Int).SetBytes(c), srv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := UnpadPKCS1v15Encryption(m.FillBytes(make([]byte, k.Size())))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.msg {
+				t.Errorf("Bleichenbacher98() = %q, want %q", got, tt.msg)
+			}
+			t.Logf("%d oracle queries", queries)
+		})
+	}
+}
+
+func TestBleichenbacher98Cancel(t *testing.T) {
+	k, err := GenerateKey(256, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewRSAPaddingServer(k)
+	c, err := srv.PublicKey().EncryptPKCS1v15([]byte("kick it, CC"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := Bleichenbacher98(ctx, srv.PublicKey(), new(big.Int).SetBytes(c), srv); err != context.Canceled {
+		t.Errorf("Bleichenbacher98() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func ExampleChallenge47() {
+	k, err := GenerateKey(256, 3)
+	if err != nil {
+		fmt.Println(err)
+	}
+	srv := NewRSAPaddingServer(k)
+	c, err := srv.PublicKey().EncryptPKCS1v15([]byte("kick it, CC"))
+	if err != nil {
+		fmt.Println(err)
+	}
+	m, _, err := Bleichenbacher98(context.Background(), srv.PublicKey(), new(big.Int).SetBytes(c), srv)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	p, err := UnpadPKCS1v15Encryption(m.FillBytes(make([]byte, k.Size())))
+	fmt.Printf("%q %v\n", p, err)
+	// output:
+	// "kick it, CC" <nil>
+}
@@ -0,0 +1,1041 @@
+package cryptopals
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rc4"
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"math/bits"
+	mathrand "math/rand"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// CBCMAC returns the CBC-MAC of msg: the last ciphertext block of its AES-CBC encryption under key and iv.
+func CBCMAC(key, iv, msg []byte) ([]byte, error) {
+	if msg == nil {
+		msg = []byte{}
+	}
+	c, err := EncryptAESCBC(msg, key, iv)
+	if err != nil {
+		return nil, err
+	}
+	return c[len(c)-aes.BlockSize:], nil
+}
+
+// CBCMACBank is an http.Handler for a toy bank that authenticates transfer requests with CBC-MAC.
+//
+// With a caller-supplied IV the request body is message || IV || MAC and the message is
+// "from=#{from_id}&to=#{to_id}&amount=#{amount}". With a fixed zero IV the body is message || MAC
+// and the message is "from=#{from_id}&tx_list=#{to:amount(;to:amount)*}".
+type CBCMACBank struct {
+	FixedIV bool
+
+	key      []byte
+	mu       sync.Mutex
+	balances map[int]int
+}
+
+// NewCBCMACBank returns a bank that shares key with its clients.
+func NewCBCMACBank(key []byte, fixedIV bool) *CBCMACBank {
+	return &CBCMACBank{
+		FixedIV:  fixedIV,
+		key:      key,
+		balances: make(map[int]int),
+	}
+}
+
+// Balance returns the balance of an account.
+func (b *CBCMACBank) Balance(id int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.balances[id]
+}
+
+// Deposit adds amount to an account.
+func (b *CBCMACBank) Deposit(id, amount int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.balances[id] += amount
+}
+
+// ServeHTTP satisfies the http.Handler interface.
+func (b *CBCMACBank) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	iv := make([]byte, aes.BlockSize)
+	tail := aes.BlockSize
+	if !b.FixedIV {
+		tail += aes.BlockSize
+	}
+	if len(body) < tail {
+		http.Error(w, "request too short", http.StatusBadRequest)
+		return
+	}
+	msg, mac := body[:len(body)-tail], body[len(body)-aes.BlockSize:]
+	if !b.FixedIV {
+		iv = body[len(body)-tail : len(body)-aes.BlockSize]
+	}
+	want, err := CBCMAC(b.key, iv, msg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if subtle.ConstantTimeCompare(mac, want) != 1 {
+		http.Error(w, "invalid mac", http.StatusForbidden)
+		return
+	}
+
+	var from int
+	var txs []cbcmacTx
+	if b.FixedIV {
+		from, txs, err = parseCBCMACTransferList(msg)
+	} else {
+		from, txs, err = parseCBCMACTransfer(msg)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	b.mu.Lock()
+	for _, tx := range txs {
+		b.balances[from] -= tx.amount
+		b.balances[tx.to] += tx.amount
+	}
+	b.mu.Unlock()
+	fmt.Fprintf(w, "ok: %d transfer(s) from %d\n", len(txs), from)
+}
+
+type cbcmacTx struct {
+	to, amount int
+}
+
+func parseCBCMACTransfer(msg []byte) (int, []cbcmacTx, error) {
+	v, err := url.ParseQuery(string(msg))
+	if err != nil {
+		return 0, nil, err
+	}
+	from, err := strconv.Atoi(v.Get("from"))
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "from")
+	}
+	to, err := strconv.Atoi(v.Get("to"))
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "to")
+	}
+	amount, err := strconv.Atoi(v.Get("amount"))
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "amount")
+	}
+	return from, []cbcmacTx{{to, amount}}, nil
+}
+
+// parseCBCMACTransferList parses the fixed-IV message format. Like many real parsers it skips
+// transactions it can't make sense of instead of rejecting the whole message.
+func parseCBCMACTransferList(msg []byte) (int, []cbcmacTx, error) {
+	s := string(msg)
+	if !strings.HasPrefix(s, "from=") {
+		return 0, nil, errors.New("missing from")
+	}
+	i := strings.Index(s, "&tx_list=")
+	if i < 0 {
+		return 0, nil, errors.New("missing tx_list")
+	}
+	from, err := strconv.Atoi(s[len("from="):i])
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "from")
+	}
+	var txs []cbcmacTx
+	for _, entry := range strings.Split(s[i+len("&tx_list="):], ";") {
+		parts := strings.Split(entry, ":")
+		if len(parts) != 2 {
+			continue
+		}
+		to, err := strconv.Atoi(parts[0])
+		if err != nil {
+			continue
+		}
+		amount, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+		txs = append(txs, cbcmacTx{to, amount})
+	}
+	return from, txs, nil
+}
+
+// CBCMACBankClient signs transfer requests on behalf of a single account.
+type CBCMACBankClient struct {
+	ID int
+
+	key []byte
+}
+
+// NewCBCMACBankClient returns a client for account id.
+func NewCBCMACBankClient(key []byte, id int) *CBCMACBankClient {
+	return &CBCMACBankClient{ID: id, key: key}
+}
+
+// Transfer returns a request body for the caller-supplied IV protocol.
+func (c *CBCMACBankClient) Transfer(to, amount int) ([]byte, error) {
+	msg := []byte(fmt.Sprintf("from=%d&to=%d&amount=%d", c.ID, to, amount))
+	iv := RandomNBytes(aes.BlockSize)
+	mac, err := CBCMAC(c.key, iv, msg)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(msg, iv...), mac...), nil
+}
+
+// TransferList returns a request body for the fixed-IV protocol. Transactions are "to:amount" pairs.
+func (c *CBCMACBankClient) TransferList(txs ...string) ([]byte, error) {
+	msg := []byte(fmt.Sprintf("from=%d&tx_list=%s", c.ID, strings.Join(txs, ";")))
+	mac, err := CBCMAC(c.key, make([]byte, aes.BlockSize), msg)
+	if err != nil {
+		return nil, err
+	}
+	return append(msg, mac...), nil
+}
+
+// ForgeCBCMACTransferIV rewrites a signed caller-supplied IV request so that it appears to come from another account.
+// Only the first block of the message may change, and the IV is adjusted by the same difference so the MAC still verifies.
+func ForgeCBCMACTransferIV(body []byte, from string) ([]byte, error) {
+	if len(body) < 3*aes.BlockSize {
+		return nil, ErrMismatchedLength
+	}
+	n := len(body) - 2*aes.BlockSize
+	msg := body[:n]
+	if !bytes.HasPrefix(msg, []byte("from=")) {
+		return nil, errors.New("missing from")
+	}
+	end := bytes.IndexByte(msg, '&')
+	if end < 0 {
+		return nil, errors.New("missing &")
+	}
+	forged := append([]byte("from="+from), msg[end:]...)
+	if len(forged) != len(msg) {
+		return nil, ErrMismatchedLength
+	}
+	if !bytes.Equal(forged[aes.BlockSize:], msg[aes.BlockSize:]) {
+		return nil, errors.New("change does not fit in the first block")
+	}
+	iv := make([]byte, aes.BlockSize)
+	copy(iv, body[n:n+aes.BlockSize])
+	for i := 0; i < aes.BlockSize; i++ {
+		iv[i] ^= msg[i] ^ forged[i]
+	}
+	result := append(forged, iv...)
+	return append(result, body[n+aes.BlockSize:]...), nil
+}
+
+// ForgeCBCMACTransferList splices an attacker-signed fixed-IV request onto a captured victim request.
+// The forged message is pad(victim) || (attacker[0] ^ victim MAC) || attacker[1:], which carries the attacker's MAC.
+func ForgeCBCMACTransferList(victim, attacker []byte) ([]byte, error) {
+	if len(victim) < aes.BlockSize || len(attacker) < 2*aes.BlockSize {
+		return nil, ErrMismatchedLength
+	}
+	vmsg, vmac := victim[:len(victim)-aes.BlockSize], victim[len(victim)-aes.BlockSize:]
+	amsg, amac := attacker[:len(attacker)-aes.BlockSize], attacker[len(attacker)-aes.BlockSize:]
+	forged := PKCS7Padding(vmsg, aes.BlockSize)
+	glue := make([]byte, aes.BlockSize)
+	for i := range glue {
+		glue[i] = amsg[i] ^ vmac[i]
+	}
+	forged = append(forged, glue...)
+	forged = append(forged, amsg[aes.BlockSize:]...)
+	return append(forged, amac...), nil
+}
+
+// CBCMACHashKey is the public key used when CBC-MAC is treated as a hash function.
+var CBCMACHashKey = []byte("YELLOW SUBMARINE")
+
+// CBCMACHash hashes msg with CBC-MAC under CBCMACHashKey and a zero IV.
+func CBCMACHash(msg []byte) ([]byte, error) {
+	return CBCMAC(CBCMACHashKey, make([]byte, aes.BlockSize), msg)
+}
+
+// containsAnyByte reports whether any byte of set occurs in b. Unlike bytes.ContainsAny it does not decode UTF-8.
+func containsAnyByte(b, set []byte) bool {
+	for _, c := range set {
+		if bytes.IndexByte(b, c) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ForgeCBCMACHash returns a message that starts with prefix and hashes to target under CBC-MAC with key and a zero IV.
+//
+// The prefix is filled with spaces to a block boundary and followed by one glue block. Because the result is
+// block aligned, the hash appends a full block of PKCS#7 padding, and the glue is chosen so that the state
+// entering that padding block decrypts to the target. If avoid is non-empty, more filler is added until the
+// glue contains none of its bytes.
+func ForgeCBCMACHash(key, target, prefix, avoid []byte) ([]byte, error) {
+	if len(target) != aes.BlockSize {
+		return nil, ErrMismatchedLength
+	}
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	// state needed before the final padding block: D(target) ^ padding
+	want := make([]byte, aes.BlockSize)
+	c.Decrypt(want, target)
+	for i := range want {
+		want[i] ^= aes.BlockSize
+	}
+	// state needed after the glue block: D(want)
+	c.Decrypt(want, want)
+
+	msg := append([]byte{}, prefix...)
+	for len(msg)%aes.BlockSize != 0 {
+		msg = append(msg, ' ')
+	}
+	for attempt := 0; attempt < 256; attempt++ {
+		// CBC state after msg is the last ciphertext block without padding
+		state := make([]byte, aes.BlockSize)
+		for i := 0; i < len(msg); i += aes.BlockSize {
+			for j := range state {
+				state[j] ^= msg[i+j]
+			}
+			c.Encrypt(state, state)
+		}
+		glue := make([]byte, aes.BlockSize)
+		for i := range glue {
+			glue[i] = want[i] ^ state[i]
+		}
+		if !containsAnyByte(glue, avoid) {
+			return append(msg, glue...), nil
+		}
+		msg = append(msg, bytes.Repeat([]byte{' '}, aes.BlockSize)...)
+	}
+	return nil, ErrNotFound
+}
+
+// CompressionOracle formats a request carrying a secret session cookie, compresses it and encrypts it
+// with a fresh key, and reveals only the ciphertext length.
+type CompressionOracle struct {
+	SessionID string
+	// CBC selects AES-CBC instead of the AES-CTR stream cipher.
+	CBC bool
+}
+
+// formatCompressionRequest formats the attacker-controlled body p into a request carrying sessionID.
+func formatCompressionRequest(sessionID string, p []byte) []byte {
+	return []byte(fmt.Sprintf("POST / HTTP/1.1\r\nHost: hapless.com\r\nCookie: sessionid=%s\r\nContent-Length: %d\r\n%s", sessionID, len(p), p))
+}
+
+// Length returns the length of the compressed and encrypted request with body p.
+func (o *CompressionOracle) Length(p []byte) (int, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := w.Write(formatCompressionRequest(o.SessionID, p)); err != nil {
+		return 0, err
+	}
+	if err := w.Close(); err != nil {
+		return 0, err
+	}
+	key := RandomNBytes(aes.BlockSize)
+	iv := RandomNBytes(aes.BlockSize)
+	if o.CBC {
+		c, err := EncryptAESCBC(buf.Bytes(), key, iv)
+		return len(c), err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return 0, err
+	}
+	c := make([]byte, buf.Len())
+	cipher.NewCTR(block, iv).XORKeyStream(c, buf.Bytes())
+	return len(c), nil
+}
+
+// compressionFiller returns n bytes of filler to prepend to guesses. The bytes are all distinct, so the
+// filler can't compress, and none of them appear in the base64 alphabet.
+func compressionFiller(n int) string {
+	var b []byte
+	for c := byte(0x21); c < 0x7f; c++ {
+		if !strings.ContainsRune("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/=", rune(c)) {
+			b = append(b, c)
+		}
+	}
+	for c := byte(0x80); len(b) < n; c++ {
+		b = append(b, c)
+	}
+	return string(b[:n])
+}
+
+// RecoverCompressedSecret recovers the value following known in a compressed-then-encrypted message using
+// only the ciphertext length, one character of alphabet at a time, stopping at a carriage return.
+//
+// A correct guess extends the back-reference to the secret and compresses a few bits better than a wrong
+// one, but the difference is hidden by rounding to bytes or, with a block cipher, to blocks. Candidates
+// are therefore compared under a series of probes, keeping only the shortest each time, until one remains.
+// Each probe repeats the guess a different number of times, which moves the length by odd numbers of bits
+// and also makes the short request worth compressing, and prepends up to two blocks of filler, which moves
+// it by whole bytes; one of the probes puts the correct guess just below a boundary.
+func RecoverCompressedSecret(oracle func([]byte) (int, error), known, alphabet string) (string, error) {
+	var secret []byte
+	alphabet += "\r"
+	filler := compressionFiller(2 * aes.BlockSize)
+	for {
+		candidates := []byte(alphabet)
+		for repeat := 2; repeat <= 8 && len(candidates) > 1; repeat++ {
+			for f := 0; f <= len(filler) && len(candidates) > 1; f++ {
+				var best []byte
+				bestLen := -1
+				for _, c := range candidates {
+					guess := filler[:f] + strings.Repeat(known+string(secret)+string(c), repeat)
+					n, err := oracle([]byte(guess))
+					if err != nil {
+						return "", err
+					}
+					switch {
+					case bestLen < 0 || n < bestLen:
+						best, bestLen = []byte{c}, n
+					case n == bestLen:
+						best = append(best, c)
+					}
+				}
+				candidates = best
+			}
+		}
+		if len(candidates) != 1 {
+			return string(secret), errors.Wrapf(ErrNotFound, "ambiguous guess after %q", secret)
+		}
+		if candidates[0] == '\r' {
+			return string(secret), nil
+		}
+		secret = append(secret, candidates[0])
+	}
+}
+
+// Multicollision is a chain of colliding block pairs built with Joux's method. Picking either block
+// at every stage yields one of 2**len(Pairs) messages that all reach State.
+type Multicollision struct {
+	Pairs [][2][]byte
+	State []byte
+}
+
+// JouxMulticollision builds a multicollision of 2**t messages for h starting from state, at the cost of t
+// birthday searches rather than the 2**t a generic attack would need.
+func JouxMulticollision(h *MDHash, state []byte, t int) *Multicollision {
+	m := &Multicollision{State: state}
+	m.Extend(h, t)
+	return m
+}
+
+// Extend adds t more stages, multiplying the number of colliding messages by 2**t.
+func (m *Multicollision) Extend(h *MDHash, t int) {
+	for i := 0; i < t; i++ {
+		a, b, next := h.FindCollision(m.State)
+		m.Pairs = append(m.Pairs, [2][]byte{a, b})
+		m.State = next
+	}
+}
+
+// Message returns the message selected by the bits of i, least significant bit first.
+func (m *Multicollision) Message(i uint64) []byte {
+	msg := make([]byte, 0, len(m.Pairs)*MDHashBlockSize)
+	for j, p := range m.Pairs {
+		msg = append(msg, p[(i>>uint(j))&1]...)
+	}
+	return msg
+}
+
+// Messages returns all 2**len(m.Pairs) colliding messages.
+func (m *Multicollision) Messages() [][]byte {
+	var msgs [][]byte
+	for i := uint64(0); i < 1<<uint(len(m.Pairs)); i++ {
+		msgs = append(msgs, m.Message(i))
+	}
+	return msgs
+}
+
+// CascadeCollision finds two messages that collide under both f and g, which shows that concatenating a
+// cheap hash with an expensive one, f(m) || g(m), is barely stronger than g alone.
+//
+// It builds a multicollision in f large enough that a g collision among its messages is expected by the
+// birthday bound, then walks the tree of messages with g so shared prefixes are compressed only once.
+// If no g collision turns up, f's multicollision is extended by another stage and g is searched again.
+func CascadeCollision(f, g *MDHash) (a, b []byte) {
+	m := JouxMulticollision(f, f.IV, g.Size()*8/2)
+	for {
+		seen := make(map[string]uint64)
+		var found []uint64
+		var walk func(depth int, idx uint64, state []byte) bool
+		walk = func(depth int, idx uint64, state []byte) bool {
+			if depth == len(m.Pairs) {
+				if prev, ok := seen[string(state)]; ok {
+					found = []uint64{prev, idx}
+					return true
+				}
+				seen[string(state)] = idx
+				return false
+			}
+			for bit, block := range m.Pairs[depth] {
+				if walk(depth+1, idx|uint64(bit)<<uint(depth), g.Compress(state, block)) {
+					return true
+				}
+			}
+			return false
+		}
+		if walk(0, 0, g.IV) {
+			return m.Message(found[0]), m.Message(found[1])
+		}
+		m.Extend(f, 1)
+	}
+}
+
+// ExpandableMessage is a Kelsey-Schneier expandable message: a set of messages of every length from k
+// to k+2**k-1 blocks that all reach State. Stage i offers a choice between a single block and 2**(k-1-i)+1 blocks.
+type ExpandableMessage struct {
+	Stages [][2][]byte
+	State  []byte
+}
+
+// NewExpandableMessage builds an expandable message with k stages for h starting from state.
+// Each stage is a birthday search for a collision between a single block and 2**i dummy blocks followed by one block.
+func NewExpandableMessage(h *MDHash, state []byte, k int) *ExpandableMessage {
+	e := &ExpandableMessage{State: state}
+	for i := k - 1; i >= 0; i-- {
+		dummy := make([]byte, (1<<uint(i))*MDHashBlockSize)
+		longState := h.Iterate(e.State, dummy)
+		short := make(map[string][]byte)
+		long := make(map[string][]byte)
+		for {
+			a := RandomNBytes(MDHashBlockSize)
+			sa := string(h.Compress(e.State, a))
+			if b, ok := long[sa]; ok {
+				e.Stages = append(e.Stages, [2][]byte{a, append(dummy, b...)})
+				e.State = []byte(sa)
+				break
+			}
+			short[sa] = a
+			b := RandomNBytes(MDHashBlockSize)
+			sb := string(h.Compress(longState, b))
+			if a, ok := short[sb]; ok {
+				e.Stages = append(e.Stages, [2][]byte{a, append(dummy, b...)})
+				e.State = []byte(sb)
+				break
+			}
+			long[sb] = b
+		}
+	}
+	return e
+}
+
+// MinBlocks returns the length of the shortest message in blocks.
+func (e *ExpandableMessage) MinBlocks() int {
+	return len(e.Stages)
+}
+
+// MaxBlocks returns the length of the longest message in blocks.
+func (e *ExpandableMessage) MaxBlocks() int {
+	return len(e.Stages) + 1<<uint(len(e.Stages)) - 1
+}
+
+// Message returns the message of the given number of blocks.
+func (e *ExpandableMessage) Message(blocks int) ([]byte, error) {
+	if blocks < e.MinBlocks() || blocks > e.MaxBlocks() {
+		return nil, errors.Errorf("length %d outside [%d, %d]", blocks, e.MinBlocks(), e.MaxBlocks())
+	}
+	extra := blocks - e.MinBlocks()
+	k := len(e.Stages)
+	var msg []byte
+	for j, stage := range e.Stages {
+		// stage j expands by 2**(k-1-j) blocks
+		msg = append(msg, stage[(extra>>uint(k-1-j))&1]...)
+	}
+	return msg, nil
+}
+
+// SecondPreimage finds a different message with the same length and hash as target under h.
+//
+// The intermediate states of target are indexed, an expandable message with k stages is built where
+// 2**k is at most the number of blocks in target, and random bridge blocks are tried from the expandable
+// message's final state until one lands on an intermediate state reachable at a valid length. The
+// expandable message is then sized to replace exactly the prefix before that state, so the forgery has the
+// same length, and therefore the same padding, as the target.
+func SecondPreimage(h *MDHash, target []byte) ([]byte, error) {
+	n := len(target) / MDHashBlockSize
+	k := 0
+	for 1<<uint(k+1) <= n {
+		k++
+	}
+	if k < 1 || n < k+1 {
+		return nil, errors.New("target message too short")
+	}
+
+	// intermediate state after j blocks -> j, for j that a bridge can reach
+	states := make(map[string]int)
+	state := h.IV
+	for j := 1; j <= n; j++ {
+		state = h.Compress(state, target[(j-1)*MDHashBlockSize:j*MDHashBlockSize])
+		if _, ok := states[string(state)]; !ok && j >= k+1 && j <= k+1<<uint(k) {
+			states[string(state)] = j
+		}
+	}
+
+	e := NewExpandableMessage(h, h.IV, k)
+	for {
+		bridge := RandomNBytes(MDHashBlockSize)
+		j, ok := states[string(h.Compress(e.State, bridge))]
+		if !ok {
+			continue
+		}
+		prefix, err := e.Message(j - 1)
+		if err != nil {
+			return nil, err
+		}
+		forged := append(prefix, bridge...)
+		return append(forged, target[j*MDHashBlockSize:]...), nil
+	}
+}
+
+// DiamondStructure is a binary tree of colliding blocks for the Nostradamus herding attack. Every leaf
+// state reaches the single root state through the blocks on its path.
+type DiamondStructure struct {
+	// States[0] holds the 2**k leaf states and States[k] holds only the root.
+	States [][][]byte
+	// Blocks[l][i] takes States[l][i] to States[l+1][i/2].
+	Blocks [][][]byte
+
+	h *MDHash
+}
+
+// NewDiamondStructure builds a diamond structure of 2**k random leaf states for h. Each level pairs up
+// the states of the level below and searches for a block for each that compresses both to the same
+// state; the pairs are independent, so they are searched in parallel.
+func NewDiamondStructure(h *MDHash, k int) *DiamondStructure {
+	d := &DiamondStructure{h: h}
+	leaves := make([][]byte, 1<<uint(k))
+	seen := make(map[string]bool)
+	for i := range leaves {
+		for {
+			leaves[i] = RandomNBytes(h.Size())
+			if !seen[string(leaves[i])] {
+				seen[string(leaves[i])] = true
+				break
+			}
+		}
+	}
+	d.States = append(d.States, leaves)
+
+	for level := 0; level < k; level++ {
+		states := d.States[level]
+		next := make([][]byte, len(states)/2)
+		blocks := make([][]byte, len(states))
+		pairs := make(chan int)
+		var wg sync.WaitGroup
+		for w := 0; w < runtime.NumCPU(); w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range pairs {
+					blocks[2*i], blocks[2*i+1], next[i] = findPairCollision(h, states[2*i], states[2*i+1])
+				}
+			}()
+		}
+		for i := range next {
+			pairs <- i
+		}
+		close(pairs)
+		wg.Wait()
+		d.States = append(d.States, next)
+		d.Blocks = append(d.Blocks, blocks)
+	}
+	return d
+}
+
+// findPairCollision searches for blocks a and b such that compressing a from s1 and b from s2 gives the same state.
+func findPairCollision(h *MDHash, s1, s2 []byte) (a, b, next []byte) {
+	from1 := make(map[string][]byte)
+	from2 := make(map[string][]byte)
+	for {
+		a := RandomNBytes(MDHashBlockSize)
+		sa := h.Compress(s1, a)
+		if b, ok := from2[string(sa)]; ok {
+			return a, b, sa
+		}
+		from1[string(sa)] = a
+		b := RandomNBytes(MDHashBlockSize)
+		sb := h.Compress(s2, b)
+		if a, ok := from1[string(sb)]; ok {
+			return a, b, sb
+		}
+		from2[string(sb)] = b
+	}
+}
+
+// K returns the depth of the structure.
+func (d *DiamondStructure) K() int {
+	return len(d.Blocks)
+}
+
+// Root returns the state every leaf leads to.
+func (d *DiamondStructure) Root() []byte {
+	return d.States[len(d.States)-1][0]
+}
+
+// Predict returns the hash committed to in advance for messages whose prefix is prefixLen bytes long.
+// The forged message is the prefix, one linking block and K blocks of the tree, so its length and
+// padding are fixed before the prefix is known.
+func (d *DiamondStructure) Predict(prefixLen int) ([]byte, error) {
+	if prefixLen%MDHashBlockSize != 0 {
+		return nil, ErrMismatchedLength
+	}
+	total := prefixLen + (1+d.K())*MDHashBlockSize
+	return d.h.Iterate(d.Root(), MDPadding(total)), nil
+}
+
+// Forge links prefix into the structure. The result starts with prefix and hashes to Predict(len(prefix)).
+func (d *DiamondStructure) Forge(prefix []byte) ([]byte, error) {
+	if len(prefix)%MDHashBlockSize != 0 {
+		return nil, ErrMismatchedLength
+	}
+	leaves := make(map[string]int)
+	for i, leaf := range d.States[0] {
+		leaves[string(leaf)] = i
+	}
+	state := d.h.Iterate(d.h.IV, prefix)
+	for {
+		link := RandomNBytes(MDHashBlockSize)
+		i, ok := leaves[string(d.h.Compress(state, link))]
+		if !ok {
+			continue
+		}
+		msg := append(append([]byte{}, prefix...), link...)
+		for level := range d.Blocks {
+			msg = append(msg, d.Blocks[level][i]...)
+			i /= 2
+		}
+		return msg, nil
+	}
+}
+
+// md4Condition is one of Wang et al.'s sufficient conditions on a bit of an MD4 state variable.
+// The bit is either forced to 0 or 1, or made equal to the same bit of the previously computed variable.
+type md4Condition struct {
+	bit int
+	val int // 0, 1 or md4Equal
+}
+
+const md4Equal = -1
+
+// wangRound1Conditions are the sufficient conditions on a1, d1, c1, b1, a2, ..., b4, indexed by step.
+// Bits are numbered from 0 here, so they are one less than in the paper.
+var wangRound1Conditions = [16][]md4Condition{
+	{{6, md4Equal}},
+	{{6, 0}, {7, md4Equal}, {10, md4Equal}},
+	{{6, 1}, {7, 1}, {10, 0}, {25, md4Equal}},
+	{{6, 1}, {7, 0}, {10, 0}, {25, 0}},
+	{{7, 1}, {10, 1}, {25, 0}, {13, md4Equal}},
+	{{13, 0}, {18, md4Equal}, {19, md4Equal}, {20, md4Equal}, {21, md4Equal}, {25, 1}},
+	{{12, md4Equal}, {13, 0}, {14, md4Equal}, {18, 0}, {19, 0}, {20, 1}, {21, 0}},
+	{{12, 1}, {13, 1}, {14, 0}, {16, md4Equal}, {18, 0}, {19, 0}, {20, 0}, {21, 0}},
+	{{12, 1}, {13, 1}, {14, 1}, {16, 0}, {18, 0}, {19, 0}, {20, 0}, {22, md4Equal}, {21, 1}, {25, md4Equal}},
+	{{12, 1}, {13, 1}, {14, 1}, {16, 0}, {19, 0}, {20, 1}, {21, 1}, {22, 0}, {25, 1}, {29, md4Equal}},
+	{{16, 1}, {19, 0}, {20, 0}, {21, 0}, {22, 0}, {25, 0}, {29, 1}, {31, md4Equal}},
+	{{19, 0}, {20, 1}, {21, 1}, {22, md4Equal}, {25, 1}, {29, 0}, {31, 0}},
+	{{22, 0}, {25, 0}, {26, md4Equal}, {28, md4Equal}, {29, 1}, {31, 0}},
+	{{22, 0}, {25, 0}, {26, 1}, {28, 1}, {29, 0}, {31, 1}},
+	{{18, md4Equal}, {22, 1}, {25, 1}, {26, 0}, {28, 0}, {29, 0}},
+	{{18, 0}, {25, 1}, {26, 1}, {28, 1}, {29, 0}},
+}
+
+// applyMD4Conditions returns x adjusted to satisfy conds, where prev is the previously computed state variable.
+func applyMD4Conditions(x, prev uint32, conds []md4Condition) uint32 {
+	for _, c := range conds {
+		mask := uint32(1) << uint(c.bit)
+		switch c.val {
+		case 0:
+			x &^= mask
+		case 1:
+			x |= mask
+		case md4Equal:
+			x = x&^mask | prev&mask
+		}
+	}
+	return x
+}
+
+// WangMD4Differential returns the message that differs from m by Wang et al.'s differential:
+// m1 + 2**31, m2 + 2**31 - 2**28 and m12 - 2**16.
+func WangMD4Differential(m [16]uint32) [16]uint32 {
+	m[1] += 1 << 31
+	m[2] += 1<<31 - 1<<28
+	m[12] -= 1 << 16
+	return m
+}
+
+// md4States holds a0, d0, c0, b0, the round 1 variables a1, d1, c1, b1, a2, ..., b4 and the first
+// round 2 variables a5, d5, c5, b5, in the order they are computed.
+type md4States [24]uint32
+
+// Indices into md4States of the round 2 variables with conditions.
+const (
+	md4A5 = 20 + iota
+	md4D5
+	md4C5
+)
+
+// md4Round2Condition requires bit of variable v to equal the same bit of variable ref.
+type md4Round2Condition struct {
+	v, bit, ref int
+}
+
+// wangRound2Conditions are the sufficient conditions on a5, d5 and c5, with bits numbered from 0.
+var wangRound2Conditions = []md4Round2Condition{
+	{md4A5, 18, 18}, {md4A5, 25, 19}, {md4A5, 26, 19}, {md4A5, 28, 19}, {md4A5, 31, 19},
+	{md4D5, 18, md4A5}, {md4D5, 25, 19}, {md4D5, 26, 19}, {md4D5, 28, 19}, {md4D5, 31, 19},
+	{md4C5, 25, md4D5}, {md4C5, 26, md4D5}, {md4C5, 28, md4D5}, {md4C5, 29, md4D5}, {md4C5, 31, md4D5},
+}
+
+// md4Correction flips bit (b + offset) of round 1 variable state to move bit b of a round 2 variable.
+type md4Correction struct {
+	state, offset int
+}
+
+// wangRound2Corrections lists, for a5, d5 and c5, the round 1 bits whose flip changes the message word
+// feeding that step. Flipping bit j of a1 moves m0 by 2^(j-3) and a5 by 2^j; bit j of a2 moves m4 and so
+// bit j+2 of d5; bit j of a3 moves m8 and bit j+6 of c5; bit j of c2 or d2 (whichever b2 selects in
+// F(b2, c2, d2)) moves m8 by 2^j and bit j+9 of c5.
+var wangRound2Corrections = [...][]md4Correction{
+	md4A5 - md4A5: {{4, 0}},
+	md4D5 - md4A5: {{8, -2}},
+	md4C5 - md4A5: {{12, -6}, {10, -9}, {9, -9}},
+}
+
+// message returns the message words that make round 1 produce the variables in st.
+func (st *md4States) message() [16]uint32 {
+	var m [16]uint32
+	for i := range m {
+		s := md4Round1Shifts[i%4]
+		m[i] = bits.RotateLeft32(st[i+4], -s) - st[i] - MD4F(st[i+3], st[i+2], st[i+1])
+	}
+	return m
+}
+
+// round2 computes a5, d5, c5 and b5 from the round 1 variables and m.
+func (st *md4States) round2(m [16]uint32) {
+	for j := 0; j < 4; j++ {
+		st[20+j] = MD4Round2(st[16+j], st[19+j], st[18+j], st[17+j], m[md4Round2Order[j]], md4Round2Shifts[j])
+	}
+}
+
+// holds reports whether st meets every round 1 condition and the round 2 conditions conds.
+func (st *md4States) holds(conds []md4Round2Condition) bool {
+	for i := 0; i < 16; i++ {
+		if applyMD4Conditions(st[i+4], st[i+3], wangRound1Conditions[i]) != st[i+4] {
+			return false
+		}
+	}
+	for _, c := range conds {
+		if (st[c.v]^st[c.ref])>>uint(c.bit)&1 != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// WangMD4Modify applies message modification to m so that the first round satisfies every sufficient
+// condition and a5 and d5 satisfy theirs; c5's conditions are corrected where that does not break an
+// earlier one.
+//
+// In the first round each state variable is computed, forced to meet its conditions, and the message word
+// is solved for. In the second round a5, d5 and c5 depend on m0, m4 and m8; a bit of a round 2 variable is
+// moved by flipping a bit of a round 1 variable that determines the word, then re-solving every message
+// word so that the rest of round 1 is unchanged. A flip is kept only if all conditions met so far still hold.
+func WangMD4Modify(m [16]uint32) [16]uint32 {
+	var st md4States
+	st[0], st[1], st[2], st[3] = MD4IV[0], MD4IV[3], MD4IV[2], MD4IV[1]
+	for i := 0; i < 16; i++ {
+		s := md4Round1Shifts[i%4]
+		x := MD4Round1(st[i], st[i+3], st[i+2], st[i+1], m[i], s)
+		st[i+4] = applyMD4Conditions(x, st[i+3], wangRound1Conditions[i])
+		m[i] = bits.RotateLeft32(st[i+4], -s) - st[i] - MD4F(st[i+3], st[i+2], st[i+1])
+	}
+	st.round2(m)
+
+	for n, c := range wangRound2Conditions {
+		if (st[c.v]^st[c.ref])>>uint(c.bit)&1 == 0 {
+			continue
+		}
+		for _, fix := range wangRound2Corrections[c.v-md4A5] {
+			mask := uint32(1) << uint((c.bit+fix.offset+32)%32)
+			st[fix.state] ^= mask
+			mm := st.message()
+			st.round2(mm)
+			if st.holds(wangRound2Conditions[:n+1]) {
+				m = mm
+				break
+			}
+			st[fix.state] ^= mask
+			st.round2(m)
+		}
+	}
+	return m
+}
+
+// FindMD4Collision searches for a pair of single-block messages with the same MD4 hash using Wang et al.'s
+// attack. Candidate messages are drawn from a generator seeded with seed, so the search is deterministic.
+// It returns the colliding messages and the number of candidates tried.
+func FindMD4Collision(ctx context.Context, seed int64) (a, b []byte, tries int, err error) {
+	rng := mathrand.New(mathrand.NewSource(seed))
+	for {
+		if tries%4096 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, nil, tries, err
+			}
+		}
+		tries++
+		var m [16]uint32
+		for i := range m {
+			m[i] = rng.Uint32()
+		}
+		m = WangMD4Modify(m)
+		mp := WangMD4Differential(m)
+		if m != mp && MD4Compress(MD4IV, m) == MD4Compress(MD4IV, mp) {
+			return MD4Block(m), MD4Block(mp), tries, nil
+		}
+	}
+}
+
+// RC4CookieOracle encrypts request || cookie with RC4 under a fresh random 128-bit key for every request.
+type RC4CookieOracle struct {
+	Cookie []byte
+}
+
+// Encrypt returns the RC4 encryption of request || cookie.
+func (o *RC4CookieOracle) Encrypt(request []byte) ([]byte, error) {
+	c, err := rc4.NewCipher(RandomNBytes(16))
+	if err != nil {
+		return nil, err
+	}
+	p := append(append([]byte{}, request...), o.Cookie...)
+	c.XORKeyStream(p, p)
+	return p, nil
+}
+
+// RC4 keystream bytes Z16 and Z32 (1-indexed) are biased towards 240 and 224.
+const (
+	rc4Z16Bias = 240
+	rc4Z32Bias = 224
+)
+
+// RecoverRC4Cookie recovers a cookie of cookieLen bytes, at most 32, from an oracle that appends it to a
+// request and encrypts under a fresh RC4 key each time.
+//
+// Padding the request by p bytes puts cookie bytes 15-p and 31-p at keystream positions Z16 and Z32.
+// For each padding the oracle is queried samples times, and the most frequent ciphertext byte at each
+// position, XORed with the bias, is taken as the plaintext byte. The queries are spread across all CPUs.
+func RecoverRC4Cookie(ctx context.Context, encrypt func([]byte) ([]byte, error), cookieLen, samples int) ([]byte, error) {
+	if cookieLen > 32 {
+		return nil, errors.New("cookie too long for the Z16 and Z32 biases")
+	}
+	cookie := make([]byte, cookieLen)
+	for pad := 0; pad < 16; pad++ {
+		i16, i32 := 15-pad, 31-pad
+		if i16 >= cookieLen && i32 >= cookieLen {
+			continue
+		}
+		counts16, counts32, err := rc4Counts(ctx, encrypt, pad, samples)
+		if err != nil {
+			return nil, err
+		}
+		if i16 < cookieLen {
+			cookie[i16] = mostFrequent(counts16) ^ rc4Z16Bias
+		}
+		if i32 < cookieLen {
+			cookie[i32] = mostFrequent(counts32) ^ rc4Z32Bias
+		}
+	}
+	return cookie, nil
+}
+
+// rc4Counts queries the oracle samples times with pad bytes of padding and counts the ciphertext bytes at Z16 and Z32.
+func rc4Counts(ctx context.Context, encrypt func([]byte) ([]byte, error), pad, samples int) (c16, c32 [256]int, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	request := bytes.Repeat([]byte{'A'}, pad)
+	workers := runtime.NumCPU()
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		werr error
+	)
+	for w := 0; w < workers; w++ {
+		n := samples / workers
+		if w < samples%workers {
+			n++
+		}
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			var l16, l32 [256]int
+			for i := 0; i < n; i++ {
+				if i%4096 == 0 && ctx.Err() != nil {
+					break
+				}
+				c, err := encrypt(request)
+				if err != nil {
+					mu.Lock()
+					werr = err
+					mu.Unlock()
+					cancel()
+					return
+				}
+				if len(c) > 15 {
+					l16[c[15]]++
+				}
+				if len(c) > 31 {
+					l32[c[31]]++
+				}
+			}
+			mu.Lock()
+			for i := range l16 {
+				c16[i] += l16[i]
+				c32[i] += l32[i]
+			}
+			mu.Unlock()
+		}(n)
+	}
+	wg.Wait()
+	if werr != nil {
+		return c16, c32, werr
+	}
+	return c16, c32, ctx.Err()
+}
+
+// mostFrequent returns the byte with the highest count.
+func mostFrequent(counts [256]int) byte {
+	best := 0
+	for i := range counts {
+		if counts[i] > counts[best] {
+			best = i
+		}
+	}
+	return byte(best)
+}
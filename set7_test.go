@@ -0,0 +1,505 @@
+package cryptopals
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"fmt"
+	mathrand "math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCBCMAC(t *testing.T) {
+	key := []byte("YELLOW SUBMARINE")
+	iv := make([]byte, aes.BlockSize)
+	tests := []struct {
+		name string
+		a, b []byte
+		same bool
+	}{
+		{"equal", []byte("attack at dawn"), []byte("attack at dawn"), true},
+		{"different", []byte("attack at dawn"), []byte("attack at dusk"), false},
+		{"padding matters", []byte("YELLOW SUBMARINE"), []byte("YELLOW SUBMARINE\x10\x10\x10\x10\x10\x10\x10\x10\x10\x10\x10\x10\x10\x10\x10\x10"), false},
+		{"empty", nil, []byte{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := CBCMAC(key, iv, tt.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := CBCMAC(key, iv, tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(a) != aes.BlockSize {
+				t.Errorf("len(CBCMAC()) = %d, want %d", len(a), aes.BlockSize)
+			}
+			if bytes.Equal(a, b) != tt.same {
+				t.Errorf("CBCMAC(%q) = %x, CBCMAC(%q) = %x, want same %v", tt.a, a, tt.b, b, tt.same)
+			}
+		})
+	}
+}
+
+func postTransfer(t *testing.T, url string, body []byte) int {
+	t.Helper()
+	resp, err := http.Post(url, "application/octet-stream", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestCBCMACBankIVForgery(t *testing.T) {
+	key := RandomNBytes(aes.BlockSize)
+	bank := NewCBCMACBank(key, false)
+	bank.Deposit(2, 1000000)
+	srv := httptest.NewServer(bank)
+	defer srv.Close()
+
+	attacker := NewCBCMACBankClient(key, 3)
+	body, err := attacker.Transfer(3, 1000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// tampering without fixing up the IV is rejected
+	tampered := append([]byte{}, body...)
+	tampered[5] = '2'
+	if got := postTransfer(t, srv.URL, tampered); got != http.StatusForbidden {
+		t.Errorf("tampered request status = %d, want %d", got, http.StatusForbidden)
+	}
+	forged, err := ForgeCBCMACTransferIV(body, "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := postTransfer(t, srv.URL, forged); got != http.StatusOK {
+		t.Errorf("forged request status = %d, want %d", got, http.StatusOK)
+	}
+	if got := bank.Balance(3); got != 1000000 {
+		t.Errorf("attacker balance = %d, want %d", got, 1000000)
+	}
+	if got := bank.Balance(2); got != 0 {
+		t.Errorf("victim balance = %d, want %d", got, 0)
+	}
+}
+
+func TestCBCMACBankLengthExtension(t *testing.T) {
+	key := RandomNBytes(aes.BlockSize)
+	bank := NewCBCMACBank(key, true)
+	bank.Deposit(2, 1000100)
+	srv := httptest.NewServer(bank)
+	defer srv.Close()
+
+	victim, err := NewCBCMACBankClient(key, 2).TransferList("4:100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	attacker, err := NewCBCMACBankClient(key, 3).TransferList("3:1", "3:1000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := ForgeCBCMACTransferList(victim, attacker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := postTransfer(t, srv.URL, forged); got != http.StatusOK {
+		t.Errorf("forged request status = %d, want %d", got, http.StatusOK)
+	}
+	if got := bank.Balance(3); got != 1000000 {
+		t.Errorf("attacker balance = %d, want %d", got, 1000000)
+	}
+}
+
+func ExampleChallenge49() {
+	key := RandomNBytes(aes.BlockSize)
+	bank := NewCBCMACBank(key, false)
+	bank.Deposit(2, 1000000)
+	srv := httptest.NewServer(bank)
+	defer srv.Close()
+
+	body, err := NewCBCMACBankClient(key, 3).Transfer(3, 1000000)
+	if err != nil {
+		fmt.Println(err)
+	}
+	forged, err := ForgeCBCMACTransferIV(body, "2")
+	if err != nil {
+		fmt.Println(err)
+	}
+	resp, err := http.Post(srv.URL, "application/octet-stream", bytes.NewReader(forged))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	resp.Body.Close()
+	fmt.Println(resp.StatusCode, bank.Balance(2), bank.Balance(3))
+	// output:
+	// 200 0 1000000
+}
+
+func TestContainsAnyByte(t *testing.T) {
+	tests := []struct {
+		b, set string
+		want   bool
+	}{
+		{"abc", "xyz", false},
+		{"abc", "xbz", true},
+		{"\xc3\xa9", "\xa9", true},
+		{"\xff", "\x80", false},
+		{"abc", "", false},
+	}
+	for _, tt := range tests {
+		if got := containsAnyByte([]byte(tt.b), []byte(tt.set)); got != tt.want {
+			t.Errorf("containsAnyByte(%q, %q) = %v, want %v", tt.b, tt.set, got, tt.want)
+		}
+	}
+}
+
+func TestForgeCBCMACHash(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		prefix string
+		avoid  string
+	}{
+		{"demo", "alert('MZA who was that?');\n", "alert('Ayo, the Wu is back!');\n//", "\r\n"},
+		{"empty prefix", "some other file", "", ""},
+		{"aligned prefix", "x", "YELLOW SUBMARINE", ""},
+		{"long", "a much longer target message that spans several blocks", "print('hello')  # ", "\r\n\x00"},
+		{"non-ASCII avoid", "x", "//", "\x80\xff\xc3\xa9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := CBCMACHash([]byte(tt.target))
+			if err != nil {
+				t.Fatal(err)
+			}
+			forged, err := ForgeCBCMACHash(CBCMACHashKey, target, []byte(tt.prefix), []byte(tt.avoid))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(forged, []byte(tt.prefix)) {
+				t.Errorf("ForgeCBCMACHash() = %q, want prefix %q", forged, tt.prefix)
+			}
+			if glue := forged[len(forged)-aes.BlockSize:]; containsAnyByte(glue, []byte(tt.avoid)) {
+				t.Errorf("glue %q contains one of %q", glue, tt.avoid)
+			}
+			got, err := CBCMACHash(forged)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, target) {
+				t.Errorf("CBCMACHash(forged) = %x, want %x", got, target)
+			}
+			// The glue targets a full block of PKCS#7 padding, which is only what gets hashed if the
+			// forgery is block aligned.
+			if len(forged)%aes.BlockSize != 0 {
+				t.Errorf("len(forged) = %d, want a multiple of %d", len(forged), aes.BlockSize)
+			}
+		})
+	}
+}
+
+func ExampleChallenge50() {
+	target, err := CBCMACHash([]byte("alert('MZA who was that?');\n"))
+	if err != nil {
+		fmt.Println(err)
+	}
+	fmt.Printf("%x\n", target)
+	forged, err := ForgeCBCMACHash(CBCMACHashKey, target, []byte("alert('Ayo, the Wu is back!');\n//"), []byte("\r\n"))
+	if err != nil {
+		fmt.Println(err)
+	}
+	got, err := CBCMACHash(forged)
+	fmt.Printf("%x %v\n", got, err)
+	// output:
+	// 296b8d7cb78a243dda4d0a61d33bbdd1
+	// 296b8d7cb78a243dda4d0a61d33bbdd1 <nil>
+}
+
+const base64Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/="
+
+func TestRecoverCompressedSecret(t *testing.T) {
+	tests := []struct {
+		name      string
+		sessionID string
+		cbc       bool
+	}{
+		{"stream", "TmV2ZXIgcmV2ZWFsIHRoZSBXdS1UYW5nIFNlY3JldCE=", false},
+		{"cbc", "TmV2ZXIgcmV2ZWFsIHRoZSBXdS1UYW5nIFNlY3JldCE=", true},
+		{"short stream", "c2VjcmV0", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &CompressionOracle{SessionID: tt.sessionID, CBC: tt.cbc}
+			got, err := RecoverCompressedSecret(o.Length, "sessionid=", base64Alphabet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.sessionID {
+				t.Errorf("RecoverCompressedSecret() = %q, want %q", got, tt.sessionID)
+			}
+		})
+	}
+}
+
+func ExampleChallenge51() {
+	o := &CompressionOracle{SessionID: "TmV2ZXIgcmV2ZWFsIHRoZSBXdS1UYW5nIFNlY3JldCE=", CBC: true}
+	got, err := RecoverCompressedSecret(o.Length, "sessionid=", base64Alphabet)
+	fmt.Println(got, err)
+	// output:
+	// TmV2ZXIgcmV2ZWFsIHRoZSBXdS1UYW5nIFNlY3JldCE= <nil>
+}
+
+func TestJouxMulticollision(t *testing.T) {
+	h, err := NewMDHash(16, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := JouxMulticollision(h, h.IV, 4)
+	calls := h.Calls()
+	msgs := m.Messages()
+	if len(msgs) != 16 {
+		t.Fatalf("len(Messages()) = %d, want 16", len(msgs))
+	}
+	want := h.Sum(msgs[0])
+	seen := make(map[string]bool)
+	for _, msg := range msgs {
+		if seen[string(msg)] {
+			t.Errorf("duplicate message %x", msg)
+		}
+		seen[string(msg)] = true
+		if got := h.Sum(msg); !bytes.Equal(got, want) {
+			t.Errorf("Sum(%x) = %x, want %x", msg, got, want)
+		}
+	}
+	// four birthday searches on a 16-bit state take around 4 * 2**8 calls, not 2**16.
+	t.Logf("%d compression calls for %d colliding messages", calls, len(msgs))
+	if calls > 1<<14 {
+		t.Errorf("Calls() = %d, want far fewer than %d", calls, 1<<16)
+	}
+}
+
+func TestCascadeCollision(t *testing.T) {
+	f, err := NewMDHash(16, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewMDHash(24, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := CascadeCollision(f, g)
+	if bytes.Equal(a, b) {
+		t.Fatalf("CascadeCollision() returned identical messages")
+	}
+	t.Logf("f calls: %d, g calls: %d", f.Calls(), g.Calls())
+	if !bytes.Equal(f.Sum(a), f.Sum(b)) {
+		t.Errorf("f.Sum(a) = %x, f.Sum(b) = %x", f.Sum(a), f.Sum(b))
+	}
+	if !bytes.Equal(g.Sum(a), g.Sum(b)) {
+		t.Errorf("g.Sum(a) = %x, g.Sum(b) = %x", g.Sum(a), g.Sum(b))
+	}
+}
+
+func TestExpandableMessage(t *testing.T) {
+	h, err := NewMDHash(16, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewExpandableMessage(h, h.IV, 4)
+	if e.MinBlocks() != 4 || e.MaxBlocks() != 19 {
+		t.Errorf("lengths = [%d, %d], want [4, 19]", e.MinBlocks(), e.MaxBlocks())
+	}
+	for blocks := e.MinBlocks(); blocks <= e.MaxBlocks(); blocks++ {
+		msg, err := e.Message(blocks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(msg) != blocks*MDHashBlockSize {
+			t.Errorf("len(Message(%d)) = %d blocks", blocks, len(msg)/MDHashBlockSize)
+		}
+		if got := h.Iterate(h.IV, msg); !bytes.Equal(got, e.State) {
+			t.Errorf("Message(%d) reaches %x, want %x", blocks, got, e.State)
+		}
+	}
+	if _, err := e.Message(e.MaxBlocks() + 1); err == nil {
+		t.Errorf("Message(%d) error = nil, want error", e.MaxBlocks()+1)
+	}
+}
+
+func TestSecondPreimage(t *testing.T) {
+	tests := []struct {
+		name   string
+		bits   int
+		length int
+	}{
+		{"16 bit", 16, 1<<10*MDHashBlockSize + 5},
+		{"24 bit", 24, 1 << 12 * MDHashBlockSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := NewMDHash(tt.bits, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			target := RandomNBytes(tt.length)
+			forged, err := SecondPreimage(h, target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Equal(forged, target) {
+				t.Fatal("SecondPreimage() returned the target")
+			}
+			if len(forged) != len(target) {
+				t.Errorf("len(forged) = %d, want %d", len(forged), len(target))
+			}
+			if got, want := h.Sum(forged), h.Sum(target); !bytes.Equal(got, want) {
+				t.Errorf("Sum(forged) = %x, want %x", got, want)
+			}
+		})
+	}
+}
+
+func TestDiamondStructure(t *testing.T) {
+	tests := []struct {
+		name string
+		bits int
+		k    int
+	}{
+		{"16 bit", 16, 6},
+		{"24 bit", 24, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := NewMDHash(tt.bits, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := NewDiamondStructure(h, tt.k)
+			t.Logf("%d compression calls to build", h.Calls())
+			for l := range d.Blocks {
+				for i, block := range d.Blocks[l] {
+					if got := h.Compress(d.States[l][i], block); !bytes.Equal(got, d.States[l+1][i/2]) {
+						t.Fatalf("level %d state %d leads to %x, want %x", l, i, got, d.States[l+1][i/2])
+					}
+				}
+			}
+
+			prefix := PKCS7Padding([]byte("Red Sox 7, Yankees 3; Dodgers 4, Giants 2"), MDHashBlockSize)
+			prediction, err := d.Predict(len(prefix))
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg, err := d.Forge(prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(msg, prefix) {
+				t.Errorf("Forge() does not start with the prefix")
+			}
+			if got := h.Sum(msg); !bytes.Equal(got, prediction) {
+				t.Errorf("Sum(forged) = %x, want prediction %x", got, prediction)
+			}
+		})
+	}
+}
+
+func TestWangMD4Modify(t *testing.T) {
+	rng := mathrand.New(mathrand.NewSource(1))
+	for n := 0; n < 1000; n++ {
+		var m [16]uint32
+		for i := range m {
+			m[i] = rng.Uint32()
+		}
+		m = WangMD4Modify(m)
+
+		// replay round 1 and check every condition
+		a, b, c, d := MD4IV[0], MD4IV[1], MD4IV[2], MD4IV[3]
+		prev := b
+		for i := 0; i < 16; i++ {
+			var x uint32
+			switch i % 4 {
+			case 0:
+				a = MD4Round1(a, b, c, d, m[i], 3)
+				x = a
+			case 1:
+				d = MD4Round1(d, a, b, c, m[i], 7)
+				x = d
+			case 2:
+				c = MD4Round1(c, d, a, b, m[i], 11)
+				x = c
+			case 3:
+				b = MD4Round1(b, c, d, a, m[i], 19)
+				x = b
+			}
+			if got := applyMD4Conditions(x, prev, wangRound1Conditions[i]); got != x {
+				t.Fatalf("round 1 step %d: %08x does not meet its conditions (want %08x)", i, x, got)
+			}
+			prev = x
+		}
+
+		// a, b, c, d now hold a4, b4, c4, d4; check the round 2 conditions on a5 and d5
+		c4, b4 := c, b
+		a5 := MD4Round2(a, b, c, d, m[0], 3)
+		d5 := MD4Round2(d, a5, b, c, m[4], 5)
+		for _, cond := range []struct {
+			name   string
+			x, ref uint32
+			bits   []uint
+		}{
+			{"a5 = c4", a5, c4, []uint{18}},
+			{"a5 = b4", a5, b4, []uint{25, 26, 28, 31}},
+			{"d5 = a5", d5, a5, []uint{18}},
+			{"d5 = b4", d5, b4, []uint{25, 26, 28, 31}},
+		} {
+			for _, bit := range cond.bits {
+				if (cond.x^cond.ref)>>bit&1 != 0 {
+					t.Fatalf("round 2: bit %d of %s does not hold", bit, cond.name)
+				}
+			}
+		}
+	}
+}
+
+func ExampleChallenge55() {
+	a, b, tries, err := FindMD4Collision(context.Background(), 0)
+	if err != nil {
+		fmt.Println(err)
+	}
+	fmt.Println(tries, bytes.Equal(a, b))
+	fmt.Printf("%x\n%x\n", MD4Sum(a), MD4Sum(b))
+	// output:
+	// 35982 false
+	// eca049d975f5b815eb027263d7c61045
+	// eca049d975f5b815eb027263d7c61045
+}
+
+func TestRecoverRC4Cookie(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping RC4 bias attack in short mode")
+	}
+	oracle := &RC4CookieOracle{Cookie: []byte("OK")}
+	got, err := RecoverRC4Cookie(context.Background(), oracle.Encrypt, len(oracle.Cookie), 1<<22)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, oracle.Cookie) {
+		t.Errorf("RecoverRC4Cookie() = %q, want %q", got, oracle.Cookie)
+	}
+}
+
+func TestRecoverRC4CookieCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	oracle := &RC4CookieOracle{Cookie: []byte("OK")}
+	if _, err := RecoverRC4Cookie(ctx, oracle.Encrypt, 2, 1<<20); err != context.Canceled {
+		t.Errorf("RecoverRC4Cookie() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := RecoverRC4Cookie(ctx, oracle.Encrypt, 33, 1); err == nil {
+		t.Error("RecoverRC4Cookie() accepted a 33-byte cookie")
+	}
+}
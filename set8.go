@@ -0,0 +1,909 @@
+package cryptopals
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DHGroup is a prime-order subgroup of the multiplicative group modulo P, generated by G and of order Q.
+type DHGroup struct {
+	P, G, Q *big.Int
+}
+
+// Challenge57Group is the group used in challenge 57.
+var Challenge57Group = DHGroup{
+	P: mustInt("7199773997391911030609999317773941274322764333428698921736339643928346453700085358802973900485592910475480089726140708102474957429903531369589969318716771", 10),
+	G: mustInt("4565356397095740655436854503483826832136106141639563487732438195343690437606117828318042418238184896212352329118608100083187535033402010599512641674644143", 10),
+	Q: mustInt("236234353446506858198510045061214171961", 10),
+}
+
+// Challenge58Group is the group used in challenge 58, whose cofactor has too few small factors to
+// recover a key by confinement alone.
+var Challenge58Group = DHGroup{
+	P: mustInt("11470374874925275658116663507232161402086650258453896274534991676898999262641581519101074740642369848233294239851519212341844337347119899874391456329785623", 10),
+	G: mustInt("622952335333961296978159266084741085889881358738459939978290179936063635566740258555167783009058567397963466103140082647486611657350811560630587013183357", 10),
+	Q: mustInt("335062023296420808191071248367701059461", 10),
+}
+
+// Cofactor returns j = (p-1)/q.
+func (g DHGroup) Cofactor() *big.Int {
+	j := new(big.Int).Sub(g.P, bigOne)
+	return j.Quo(j, g.Q)
+}
+
+// SmallFactors returns the distinct prime factors of n below bound, found by trial division.
+func SmallFactors(n *big.Int, bound uint64) []*big.Int {
+	var (
+		factors []*big.Int
+		rest    = new(big.Int).Set(n)
+		d       = new(big.Int)
+		r       = new(big.Int)
+	)
+	for f := uint64(2); f < bound && rest.Cmp(bigOne) > 0; f++ {
+		d.SetUint64(f)
+		if r.Rem(rest, d).Sign() != 0 {
+			continue
+		}
+		factors = append(factors, new(big.Int).Set(d))
+		for r.Rem(rest, d).Sign() == 0 {
+			rest.Quo(rest, d)
+		}
+	}
+	return factors
+}
+
+// ElementOfOrder returns a random element of order r modulo p, where r is a prime dividing p-1.
+func ElementOfOrder(p, r *big.Int) (*big.Int, error) {
+	e := new(big.Int).Sub(p, bigOne)
+	if new(big.Int).Rem(e, r).Sign() != 0 {
+		return nil, errors.Errorf("%v does not divide p-1", r)
+	}
+	e.Quo(e, r)
+	for {
+		h, err := rand.Int(rand.Reader, p)
+		if err != nil {
+			return nil, err
+		}
+		if h.Sign() == 0 {
+			continue
+		}
+		if h.Exp(h, e, p); h.Cmp(bigOne) != 0 {
+			return h, nil
+		}
+	}
+}
+
+// DHMACOracle answers a Diffie-Hellman handshake with an attacker-chosen public key by returning a
+// message and its MAC under the resulting shared secret.
+type DHMACOracle interface {
+	MAC(h *big.Int) (msg, mac []byte, err error)
+}
+
+// dhMAC returns HMAC-SHA256(K, msg).
+func dhMAC(K *big.Int, msg []byte) []byte {
+	m := hmac.New(sha256.New, K.Bytes())
+	m.Write(msg)
+	return m.Sum(nil)
+}
+
+// DHBob is a DHMACOracle that does not validate the public keys it is given.
+type DHBob struct {
+	group DHGroup
+	x     *big.Int
+	calls atomic.Int64
+}
+
+// NewDHBob returns a DHBob with a random private key in [1, q).
+func NewDHBob(group DHGroup) (*DHBob, error) {
+	x, err := randRange(bigOne, group.Q)
+	if err != nil {
+		return nil, err
+	}
+	return NewDHBobWithKey(group, x), nil
+}
+
+// NewDHBobWithKey returns a DHBob with private key x.
+func NewDHBobWithKey(group DHGroup, x *big.Int) *DHBob {
+	return &DHBob{group: group, x: new(big.Int).Set(x)}
+}
+
+// PublicKey returns g^x mod p.
+func (b *DHBob) PublicKey() *big.Int {
+	return new(big.Int).Exp(b.group.G, b.x, b.group.P)
+}
+
+// MAC returns a fixed message and its MAC under h^x mod p.
+func (b *DHBob) MAC(h *big.Int) (msg, mac []byte, err error) {
+	b.calls.Add(1)
+	if h.Sign() <= 0 || h.Cmp(b.group.P) >= 0 {
+		return nil, nil, errors.New("public key out of range")
+	}
+	msg = []byte("crazy flamboyant for the rap enjoyment")
+	return msg, dhMAC(new(big.Int).Exp(h, b.x, b.group.P), msg), nil
+}
+
+// Calls returns the number of times MAC has been called.
+func (b *DHBob) Calls() int64 {
+	return b.calls.Load()
+}
+
+// SmallSubgroupLeak recovers x mod r from the oracle for each prime r < bound dividing (p-1)/q, by
+// sending an element h of order r and brute forcing the MAC key h^x. It stops once the product of the
+// moduli exceeds limit, or when the factors run out, and returns x mod n where n is that product.
+func SmallSubgroupLeak(group DHGroup, oracle DHMACOracle, bound uint64, limit *big.Int) (x, n *big.Int, err error) {
+	var residues, moduli []*big.Int
+	n = big.NewInt(1)
+	for _, r := range SmallFactors(group.Cofactor(), bound) {
+		if n.Cmp(limit) > 0 {
+			break
+		}
+		h, err := ElementOfOrder(group.P, r)
+		if err != nil {
+			return nil, nil, err
+		}
+		msg, mac, err := oracle.MAC(h)
+		if err != nil {
+			return nil, nil, err
+		}
+		b, err := bruteForceSubgroupKey(group.P, h, r, msg, mac)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "residue mod %v", r)
+		}
+		residues = append(residues, b)
+		moduli = append(moduli, r)
+		n.Mul(n, r)
+	}
+	if len(moduli) == 0 {
+		return nil, nil, ErrNotFound
+	}
+	return CRT(residues, moduli)
+}
+
+// bruteForceSubgroupKey returns the b in [0, r) for which mac is the MAC of msg under h^b mod p.
+func bruteForceSubgroupKey(p, h, r *big.Int, msg, mac []byte) (*big.Int, error) {
+	K := big.NewInt(1)
+	for b := new(big.Int); b.Cmp(r) < 0; b.Add(b, bigOne) {
+		if hmac.Equal(dhMAC(K, msg), mac) {
+			return b, nil
+		}
+		K.Mul(K, h).Mod(K, p)
+	}
+	return nil, ErrNotFound
+}
+
+// RecoverDHKeySmallSubgroup recovers the oracle's private key when the small factors of (p-1)/q below
+// bound multiply to more than q.
+func RecoverDHKeySmallSubgroup(group DHGroup, oracle DHMACOracle, bound uint64) (*big.Int, error) {
+	x, n, err := SmallSubgroupLeak(group, oracle, bound, group.Q)
+	if err != nil {
+		return nil, err
+	}
+	if n.Cmp(group.Q) <= 0 {
+		return nil, errors.New("small factors do not cover q")
+	}
+	return x, nil
+}
+
+// RecoverDHKeyWithKangaroo recovers the private key behind the oracle's public key y. Confinement to
+// the subgroups of order r < bound leaks x mod n; the rest, x = n*m + (x mod n), is found by
+// catching m in [0, (q-1)/n] with Pollard's kangaroo in the subgroup generated by g^n, retrying with a
+// different jump set if the wild kangaroo escapes.
+func RecoverDHKeyWithKangaroo(group DHGroup, oracle DHMACOracle, y *big.Int, bound uint64) (*big.Int, error) {
+	x, n, err := SmallSubgroupLeak(group, oracle, bound, group.Q)
+	if err != nil {
+		return nil, err
+	}
+	if n.Cmp(group.Q) > 0 {
+		return x, nil
+	}
+	// y' = y * g^-x = (g^n)^m.
+	gn := new(big.Int).Exp(group.G, n, group.P)
+	yp := new(big.Int).Exp(group.G, new(big.Int).Sub(group.Q, x), group.P)
+	yp.Mul(yp, y).Mod(yp, group.P)
+	hi := new(big.Int).Sub(group.Q, bigOne)
+	hi.Quo(hi, n)
+	// The kangaroo can miss. Each retry doubles the mean jump, which changes the jump set and lengthens
+	// the tame kangaroo's run.
+	mean := new(big.Int).Sqrt(hi)
+	mean.Rsh(mean, 1)
+	for attempt := 0; ; attempt++ {
+		kg := &Kangaroo{Group: ModPGroup{P: group.P}, Mean: mean}
+		m, err := kg.Catch(gn, yp, new(big.Int), hi)
+		if err == nil {
+			return m.Mul(m, n).Add(m, x), nil
+		}
+		if err != ErrNotFound || attempt == kangarooRetries {
+			return nil, errors.Wrap(err, "kangaroo")
+		}
+		mean = new(big.Int).Lsh(mean, 1)
+	}
+}
+
+// kangarooRetries is the number of times RecoverDHKeyWithKangaroo retries a kangaroo that escaped.
+const kangarooRetries = 3
+
+// ECDHMACOracle answers an ECDH handshake with an attacker-chosen public point by returning a message
+// and its MAC under the resulting shared point.
+type ECDHMACOracle interface {
+	MAC(pub *ECPoint) (msg, mac []byte, err error)
+}
+
+// ecdhMAC returns HMAC-SHA256 of msg keyed by both coordinates of the shared point.
+func ecdhMAC(curve *WeierstrassCurve, shared *ECPoint, msg []byte) []byte {
+	size := (curve.P.BitLen() + 7) / 8
+	key := make([]byte, 2*size)
+	if !shared.IsIdentity() {
+		shared.X.FillBytes(key[:size])
+		shared.Y.FillBytes(key[size:])
+	}
+	m := hmac.New(sha256.New, key)
+	m.Write(msg)
+	return m.Sum(nil)
+}
+
+// ECDHBob is an ECDHMACOracle. Unless Validate is set it does not check that public points are on its curve.
+type ECDHBob struct {
+	Validate bool
+
+	curve *WeierstrassCurve
+	d     *big.Int
+	pub   *ECPoint
+	calls atomic.Int64
+}
+
+// NewECDHBob returns an ECDHBob with a random key on curve.
+func NewECDHBob(curve *WeierstrassCurve) (*ECDHBob, error) {
+	d, pub, err := curve.GenerateECKey()
+	if err != nil {
+		return nil, err
+	}
+	return &ECDHBob{curve: curve, d: d, pub: pub}, nil
+}
+
+// NewECDHBobWithKey returns an ECDHBob with private key d on curve.
+func NewECDHBobWithKey(curve *WeierstrassCurve, d *big.Int) *ECDHBob {
+	return &ECDHBob{curve: curve, d: new(big.Int).Set(d), pub: curve.ScalarMult(curve.G, d)}
+}
+
+// PublicKey returns Bob's public point.
+func (b *ECDHBob) PublicKey() *ECPoint {
+	return b.pub
+}
+
+// MAC returns a fixed message and its MAC under d*pub.
+func (b *ECDHBob) MAC(pub *ECPoint) (msg, mac []byte, err error) {
+	b.calls.Add(1)
+	if b.Validate && !b.curve.IsOnCurve(pub) {
+		return nil, nil, ErrInvalidPoint
+	}
+	msg = []byte("crazy flamboyant for the rap enjoyment")
+	return msg, ecdhMAC(b.curve, b.curve.ScalarMult(pub, b.d), msg), nil
+}
+
+// Calls returns the number of times MAC has been called.
+func (b *ECDHBob) Calls() int64 {
+	return b.calls.Load()
+}
+
+// InvalidCurve is a curve sharing a and p with a target curve but with a different b, and its order.
+type InvalidCurve struct {
+	B, Order *big.Int
+}
+
+// Challenge59InvalidCurves are the invalid curves suggested in challenge 59 for Challenge59Curve.
+var Challenge59InvalidCurves = []InvalidCurve{
+	{B: big.NewInt(210), Order: mustInt("233970423115425145550826547352470124412", 10)},
+	{B: big.NewInt(504), Order: mustInt("233970423115425145544350131142039591210", 10)},
+	{B: big.NewInt(727), Order: mustInt("233970423115425145545378039958152057148", 10)},
+}
+
+// RecoverECDHKeyInvalidCurve recovers the oracle's private key by sending it points of small prime
+// order r < bound taken from invalid curves, brute forcing each d mod r from the MAC, and combining
+// the residues with the CRT once their moduli multiply to more than the order of the base point.
+func RecoverECDHKeyInvalidCurve(curve *WeierstrassCurve, oracle ECDHMACOracle, invalid []InvalidCurve, bound uint64) (*big.Int, error) {
+	var residues, moduli []*big.Int
+	n := big.NewInt(1)
+	used := make(map[string]bool)
+	for _, ic := range invalid {
+		bad := curve.WithB(ic.B)
+		for _, r := range SmallFactors(ic.Order, bound) {
+			if n.Cmp(curve.N) > 0 {
+				break
+			}
+			if used[r.String()] {
+				continue
+			}
+			p, err := bad.PointOfOrder(ic.Order, r)
+			if err != nil {
+				return nil, err
+			}
+			msg, mac, err := oracle.MAC(p)
+			if err != nil {
+				return nil, err
+			}
+			k, err := bruteForceECSubgroupKey(curve, p, r, msg, mac)
+			if err != nil {
+				return nil, errors.Wrapf(err, "residue mod %v", r)
+			}
+			used[r.String()] = true
+			residues = append(residues, k)
+			moduli = append(moduli, r)
+			n.Mul(n, r)
+		}
+	}
+	if n.Cmp(curve.N) <= 0 {
+		return nil, errors.New("small subgroups do not cover the base point order")
+	}
+	d, _, err := CRT(residues, moduli)
+	return d, err
+}
+
+// bruteForceECSubgroupKey returns the k in [0, r) for which mac is the MAC of msg under kp.
+func bruteForceECSubgroupKey(curve *WeierstrassCurve, p *ECPoint, r *big.Int, msg, mac []byte) (*big.Int, error) {
+	q := curve.Identity()
+	for k := new(big.Int); k.Cmp(r) < 0; k.Add(k, bigOne) {
+		if hmac.Equal(ecdhMAC(curve, q, msg), mac) {
+			return k, nil
+		}
+		q = curve.Add(q, p)
+	}
+	return nil, ErrNotFound
+}
+
+// XECDHMACOracle is an ECDHMACOracle for x-only ECDH: it is sent a u coordinate and MACs a message
+// under the u coordinate of the shared point.
+type XECDHMACOracle interface {
+	MAC(u *big.Int) (msg, mac []byte, err error)
+}
+
+// xecdhMAC returns HMAC-SHA256 of msg keyed by the shared u coordinate.
+func xecdhMAC(curve *MontgomeryCurve, u *big.Int, msg []byte) []byte {
+	key := u.FillBytes(make([]byte, (curve.P.BitLen()+7)/8))
+	m := hmac.New(sha256.New, key)
+	m.Write(msg)
+	return m.Sum(nil)
+}
+
+// XECDHBob is an XECDHMACOracle using the Montgomery ladder. It never checks whether u is on the
+// curve or its twist.
+type XECDHBob struct {
+	curve *MontgomeryCurve
+	d     *big.Int
+	calls atomic.Int64
+}
+
+// NewXECDHBob returns an XECDHBob with a random key on curve.
+func NewXECDHBob(curve *MontgomeryCurve) (*XECDHBob, error) {
+	d, err := randRange(bigOne, curve.N)
+	if err != nil {
+		return nil, err
+	}
+	return &XECDHBob{curve: curve, d: d}, nil
+}
+
+// PublicKey returns the u coordinate of Bob's public point.
+func (b *XECDHBob) PublicKey() *big.Int {
+	return b.curve.Ladder(b.curve.U, b.d)
+}
+
+// MAC returns a fixed message and its MAC under ladder(u, d).
+func (b *XECDHBob) MAC(u *big.Int) (msg, mac []byte, err error) {
+	b.calls.Add(1)
+	msg = []byte("crazy flamboyant for the rap enjoyment")
+	return msg, xecdhMAC(b.curve, b.curve.Ladder(u, b.d), msg), nil
+}
+
+// Calls returns the number of times MAC has been called.
+func (b *XECDHBob) Calls() int64 {
+	return b.calls.Load()
+}
+
+// twistPointOfOrder returns the u coordinate of a random twist point whose order is the product of
+// primes, each of which divides the twist order exactly once.
+func twistPointOfOrder(curve *MontgomeryCurve, primes []*big.Int) (*big.Int, error) {
+	n := big.NewInt(1)
+	for _, r := range primes {
+		n.Mul(n, r)
+	}
+	h := new(big.Int).Quo(curve.TwistOrder(), n)
+	for {
+		u, err := rand.Int(rand.Reader, curve.P)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.OnTwist(u) {
+			continue
+		}
+		u = curve.Ladder(u, h)
+		ok := u.Sign() != 0
+		for _, r := range primes {
+			if ok && curve.Ladder(u, new(big.Int).Quo(n, r)).Sign() == 0 {
+				ok = false
+			}
+		}
+		if ok {
+			return u, nil
+		}
+	}
+}
+
+// bruteForceTwistKey returns a k in [0, r/2] for which mac is the MAC of msg under ladder(u, k). Since
+// only u coordinates are compared, r-k matches as well. Multiples of u are stepped through with
+// x-only differential additions rather than a ladder per guess.
+func bruteForceTwistKey(curve *MontgomeryCurve, u, r *big.Int, msg, mac []byte) (*big.Int, error) {
+	if hmac.Equal(xecdhMAC(curve, new(big.Int), msg), mac) {
+		return new(big.Int), nil
+	}
+	prevX, prevZ := new(big.Int).Set(u), big.NewInt(1)
+	x, z := new(big.Int).Set(u), big.NewInt(1)
+	half := new(big.Int).Rsh(r, 1)
+	for k := big.NewInt(1); k.Cmp(half) <= 0; k.Add(k, bigOne) {
+		if hmac.Equal(xecdhMAC(curve, curve.affineU(x, z), msg), mac) {
+			return k, nil
+		}
+		var nx, nz *big.Int
+		if k.Cmp(bigOne) == 0 {
+			nx, nz = curve.montgomeryDouble(x, z)
+		} else {
+			nx, nz = curve.montgomeryAdd(x, z, u, bigOne, prevX, prevZ)
+		}
+		prevX, prevZ, x, z = x, z, nx, nz
+	}
+	return nil, ErrNotFound
+}
+
+// TwistLeak recovers d mod R up to sign from an x-only oracle, where R is the product of the odd primes
+// r < bound dividing the order of the quadratic twist, which the ladder never notices it is sent points on.
+//
+// Each twist point of order r reveals d mod r only up to sign. The signs are reconciled one prime at a
+// time by sending a point of order R*r, where R is the product of the primes so far, and checking which
+// CRT combination matches, so that d = ±x mod R.
+func TwistLeak(curve *MontgomeryCurve, oracle XECDHMACOracle, bound uint64) (x, R *big.Int, err error) {
+	var primes []*big.Int
+	x, R = new(big.Int), big.NewInt(1)
+	for _, r := range SmallFactors(curve.TwistOrder(), bound) {
+		if r.Cmp(big.NewInt(2)) == 0 {
+			continue
+		}
+		u, err := twistPointOfOrder(curve, []*big.Int{r})
+		if err != nil {
+			return nil, nil, err
+		}
+		msg, mac, err := oracle.MAC(u)
+		if err != nil {
+			return nil, nil, err
+		}
+		k, err := bruteForceTwistKey(curve, u, r, msg, mac)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "residue mod %v", r)
+		}
+		primes = append(primes, r)
+		if len(primes) == 1 {
+			x, R = k, r
+			continue
+		}
+		if u, err = twistPointOfOrder(curve, primes); err != nil {
+			return nil, nil, err
+		}
+		if msg, mac, err = oracle.MAC(u); err != nil {
+			return nil, nil, err
+		}
+		var found bool
+		for _, kr := range []*big.Int{k, new(big.Int).Sub(r, k)} {
+			c, n, err := CRT([]*big.Int{x, kr}, []*big.Int{R, r})
+			if err != nil {
+				return nil, nil, err
+			}
+			if hmac.Equal(xecdhMAC(curve, curve.Ladder(u, c), msg), mac) {
+				x, R, found = c, n, true
+				break
+			}
+		}
+		if !found {
+			return nil, nil, errors.Errorf("no sign combination matches mod %v", r)
+		}
+	}
+	if len(primes) == 0 {
+		return nil, nil, ErrNotFound
+	}
+	return x, R, nil
+}
+
+// RecoverXECDHKeyTwist recovers a private key equivalent to the oracle's, whose public u coordinate is
+// pub, and returns it up to sign, which x-only ECDH cannot distinguish.
+//
+// TwistLeak gives d = ±x mod R, and lifting pub gives y = ±dG. So for some xs in {x, R-x} and
+// 0 <= m <= (N-1)/R, either y = (xs + mR)G, or -y = (xs + mR)G and y - (R-xs)G = -(m+1)RG. Searching
+// m in [-(N-1)/R - 1, (N-1)/R] with Pollard's kangaroo on the equivalent Weierstrass curve therefore
+// covers both signs of y, and at most two runs are needed.
+func RecoverXECDHKeyTwist(curve *MontgomeryCurve, oracle XECDHMACOracle, pub *big.Int, bound uint64) (*big.Int, error) {
+	x, R, err := TwistLeak(curve, oracle, bound)
+	if err != nil {
+		return nil, err
+	}
+	w := curve.Weierstrass()
+	v, ok := curve.LiftX(pub)
+	if !ok {
+		return nil, ErrInvalidPoint
+	}
+	y := curve.ToWeierstrass(pub, v)
+	g := w.ScalarMult(w.G, R)
+	hi := new(big.Int).Sub(curve.N, bigOne)
+	hi.Quo(hi, R)
+	lo := new(big.Int).Add(hi, bigOne)
+	lo.Neg(lo)
+	kg := &Kangaroo{Group: w}
+	for _, xs := range []*big.Int{x, new(big.Int).Sub(R, x)} {
+		// y - xs*G = m * (R*G)
+		yp := w.Add(y, w.Neg(w.ScalarMult(w.G, xs)))
+		m, err := kg.Catch(g, yp, lo, hi)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return m.Mul(m, R).Add(m, xs).Mod(m, curve.N), nil
+	}
+	return nil, errors.Wrap(ErrNotFound, "kangaroo")
+}
+
+// ForgeECDSAKey returns a new key, on a copy of pub's curve with a different base point, under which
+// (r, s) is also a valid signature of hashed. With R = u1 G + u2 Q, any d' gives a base point
+// G' = (u1 + u2 d')^-1 R for which R = u1 G' + u2 d' G'.
+func ForgeECDSAKey(pub *ECDSAPublicKey, hashed []byte, r, s *big.Int) (*ECDSAPrivateKey, error) {
+	c := pub.Curve
+	u1, u2, err := ecdsaScalars(pub, hashed, r, s)
+	if err != nil {
+		return nil, err
+	}
+	R := c.Add(c.ScalarMult(c.G, u1), c.ScalarMult(pub.Q, u2))
+	if R.IsIdentity() {
+		return nil, ErrInvalidSignature
+	}
+	for {
+		d, err := randRange(bigOne, c.N)
+		if err != nil {
+			return nil, err
+		}
+		t := new(big.Int).Mul(u2, d)
+		t.Add(t, u1).Mod(t, c.N)
+		tinv, err := InvMod(t, c.N)
+		if err != nil {
+			continue
+		}
+		forged := &WeierstrassCurve{A: c.A, B: c.B, P: c.P, N: c.N, G: c.ScalarMult(R, tinv)}
+		return &ECDSAPrivateKey{
+			ECDSAPublicKey: ECDSAPublicKey{Curve: forged, Q: forged.ScalarMult(forged.G, d)},
+			D:              d,
+		}, nil
+	}
+}
+
+// PohligHellman returns x with g^x = y mod p, where p-1 is the product of the given distinct primes,
+// by solving the discrete logarithm in each prime-order subgroup by brute force and combining the
+// results with the CRT. It returns ErrNotFound if y is not a power of g.
+func PohligHellman(g, y, p *big.Int, factors []*big.Int) (*big.Int, error) {
+	order := new(big.Int).Sub(p, bigOne)
+	residues := make([]*big.Int, len(factors))
+	for i, r := range factors {
+		e := new(big.Int).Quo(order, r)
+		gr := new(big.Int).Exp(g, e, p)
+		yr := new(big.Int).Exp(y, e, p)
+		acc := big.NewInt(1)
+		for k := new(big.Int); k.Cmp(r) < 0; k.Add(k, bigOne) {
+			if acc.Cmp(yr) == 0 {
+				residues[i] = new(big.Int).Set(k)
+				break
+			}
+			acc.Mul(acc, gr).Mod(acc, p)
+		}
+		if residues[i] == nil {
+			return nil, ErrNotFound
+		}
+	}
+	x, _, err := CRT(residues, factors)
+	return x, err
+}
+
+// smoothPrimeFactorBits bounds the size of the factors of p-1 chosen by smoothPrime.
+const smoothPrimeFactorBits = 16
+
+// smoothPrimeMaxMisses is the number of consecutive unusable candidates after which smoothPrime gives up
+// on the current product and starts again. Near the end only a few bits remain, and every prime of that
+// size may already be used or avoided.
+const smoothPrimeMaxMisses = 1 << 10
+
+// smoothPrime returns a prime p of the given size such that p-1 is 2 times distinct primes below
+// 2^16, none of them in avoid, along with those factors, 2 included.
+func smoothPrime(bits int, avoid map[uint64]bool) (*big.Int, []*big.Int, error) {
+attempt:
+	for {
+		prod := big.NewInt(2)
+		factors := []*big.Int{big.NewInt(2)}
+		used := map[uint64]bool{2: true}
+		for misses := 0; prod.BitLen() < bits; {
+			k := bits - prod.BitLen()
+			if k > smoothPrimeFactorBits {
+				k = smoothPrimeFactorBits
+			}
+			if k < 2 {
+				break
+			}
+			r, err := randRange(new(big.Int).Lsh(bigOne, uint(k-1)), new(big.Int).Lsh(bigOne, uint(k)))
+			if err != nil {
+				return nil, nil, err
+			}
+			if !r.ProbablyPrime(0) || used[r.Uint64()] || avoid[r.Uint64()] {
+				if misses++; misses == smoothPrimeMaxMisses {
+					continue attempt
+				}
+				continue
+			}
+			misses = 0
+			used[r.Uint64()] = true
+			factors = append(factors, r)
+			prod.Mul(prod, r)
+		}
+		p := prod.Add(prod, bigOne)
+		if p.BitLen() == bits && p.ProbablyPrime(20) {
+			return p, factors, nil
+		}
+	}
+}
+
+// isPrimitiveRoot reports whether g generates the multiplicative group mod p, given the prime factors of p-1.
+func isPrimitiveRoot(g, p *big.Int, factors []*big.Int) bool {
+	if new(big.Int).Mod(g, p).Sign() == 0 {
+		return false
+	}
+	order := new(big.Int).Sub(p, bigOne)
+	for _, r := range factors {
+		if new(big.Int).Exp(g, new(big.Int).Quo(order, r), p).Cmp(bigOne) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ForgeRSAKey returns a new RSA key, with a modulus of the same size as pub's, under which sig is also
+// a valid PKCS#1 v1.5 signature of hashed, and the time spent searching for smooth primes. The search is
+// randomized and its running time varies widely; it stops early with ctx's error if ctx is done.
+//
+// The primes p and q are chosen so that p-1 and q-1 are smooth, share only the factor 2, and have the
+// signature s as a primitive root. Pohlig-Hellman then finds e_p and e_q with s^e = m mod p and mod q,
+// where m is the padded message, and the CRT combines them into e' with s^e' = m mod pq.
+func ForgeRSAKey(ctx context.Context, pub *RSAPublicKey, hash crypto.Hash, hashed, sig []byte) (*RSAPrivateKey, time.Duration, error) {
+	if err := VerifyPKCS1v15(pub, hash, hashed, sig); err != nil {
+		return nil, 0, err
+	}
+	t, err := digestInfo(hash, hashed)
+	if err != nil {
+		return nil, 0, err
+	}
+	size := pub.Size()
+	m := new(big.Int).SetBytes(pkcs1v15SignatureBlock(t, size))
+	s := new(big.Int).SetBytes(sig)
+	bits := 8 * size
+
+	var search time.Duration
+	primitive := func(bits int, avoid map[uint64]bool) (*big.Int, []*big.Int, error) {
+		start := time.Now()
+		defer func() { search += time.Since(start) }()
+		for {
+			if err := ctx.Err(); err != nil {
+				return nil, nil, err
+			}
+			p, factors, err := smoothPrime(bits, avoid)
+			if err != nil {
+				return nil, nil, err
+			}
+			if isPrimitiveRoot(s, p, factors) {
+				return p, factors, nil
+			}
+		}
+	}
+	for {
+		p, pf, err := primitive(bits-bits/2, nil)
+		if err != nil {
+			return nil, search, err
+		}
+		avoid := make(map[uint64]bool)
+		for _, r := range pf[1:] {
+			avoid[r.Uint64()] = true
+		}
+		q, qf, err := primitive(bits/2, avoid)
+		if err != nil {
+			return nil, search, err
+		}
+		n := new(big.Int).Mul(p, q)
+		if n.BitLen() != bits || n.Cmp(s) <= 0 {
+			continue
+		}
+		ep, err := PohligHellman(s, m, p, pf)
+		if err != nil {
+			continue
+		}
+		eq, err := PohligHellman(s, m, q, qf)
+		if err != nil || ep.Bit(0) != eq.Bit(0) {
+			continue
+		}
+		// p-1 and (q-1)/2 are coprime, and e_q mod 2 already agrees with e_p.
+		pm1 := new(big.Int).Sub(p, bigOne)
+		qm1 := new(big.Int).Sub(q, bigOne)
+		h := new(big.Int).Rsh(qm1, 1)
+		e, _, err := CRT([]*big.Int{ep, eq}, []*big.Int{pm1, h})
+		if err != nil {
+			continue
+		}
+		lambda := new(big.Int).Mul(pm1, qm1)
+		lambda.Rsh(lambda, 1)
+		d, err := InvMod(e, lambda)
+		if err != nil {
+			continue
+		}
+		k := &RSAPrivateKey{RSAPublicKey: RSAPublicKey{N: n, E: e}, D: d, P: p, Q: q}
+		if err := k.Precompute(); err != nil {
+			return nil, search, err
+		}
+		return k, search, nil
+	}
+}
+
+// ECDSASignature is a signature (R, S) of Hashed.
+type ECDSASignature struct {
+	Hashed []byte
+	R, S   *big.Int
+}
+
+// BiasedNonceSigner signs with nonces whose low Bits bits are zero.
+type BiasedNonceSigner struct {
+	Key  *ECDSAPrivateKey
+	Bits uint
+}
+
+// Sign signs hashed with a biased nonce.
+func (b *BiasedNonceSigner) Sign(hashed []byte) (ECDSASignature, error) {
+	for {
+		nonce, err := randRange(bigOne, b.Key.Curve.N)
+		if err != nil {
+			return ECDSASignature{}, err
+		}
+		nonce.Rsh(nonce, b.Bits).Lsh(nonce, b.Bits)
+		r, s, err := b.Key.SignWithNonce(hashed, nonce)
+		if err == nil {
+			return ECDSASignature{Hashed: hashed, R: r, S: s}, nil
+		}
+	}
+}
+
+// HiddenNumberBasis returns the lattice basis for the hidden number problem posed by signatures whose
+// nonces k have their low bits zeroed.
+//
+// Each nonce is k = 2^l b with b < n/2^l, so d t - u = b mod n for t = r / (s 2^l) and u = -H(m) / (s 2^l).
+// The basis has a row n e_i for each signature, a row (t_0 .. t_m-1, 1/2^l, 0) and a row
+// (u_0 .. u_m-1, 0, n/2^l); the short vector (b_0 .. b_m-1, d/2^l, -n/2^l) lies in the lattice.
+func HiddenNumberBasis(n *big.Int, sigs []ECDSASignature, bits uint) (Basis, error) {
+	m := len(sigs)
+	b := make(Basis, m+2)
+	for i := range b {
+		b[i] = make([]*big.Rat, m+2)
+		for j := range b[i] {
+			b[i][j] = new(big.Rat)
+		}
+	}
+	shift := new(big.Int).Lsh(bigOne, bits)
+	for i, sig := range sigs {
+		b[i][i].SetInt(n)
+		// 1 / (s 2^l) mod n
+		den := new(big.Int).Mul(sig.S, shift)
+		inv, err := InvMod(den.Mod(den, n), n)
+		if err != nil {
+			return nil, errors.Wrapf(err, "signature %d", i)
+		}
+		t := new(big.Int).Mul(sig.R, inv)
+		b[m][i].SetInt(t.Mod(t, n))
+		u := new(big.Int).Mul(hashToInt(sig.Hashed, n), inv)
+		u.Neg(u).Mod(u, n)
+		b[m+1][i].SetInt(u)
+	}
+	b[m][m].SetFrac(bigOne, shift)
+	b[m+1][m+1].SetFrac(n, shift)
+	return b, nil
+}
+
+// RecoverECDSAKeyBiasedNonces recovers the private key behind pub from signatures whose nonces have
+// their low bits zeroed, by LLL-reducing HiddenNumberBasis and reading d off the row that ends in ±n/2^l.
+func RecoverECDSAKeyBiasedNonces(pub *ECDSAPublicKey, sigs []ECDSASignature, bits uint) (*big.Int, error) {
+	n := pub.Curve.N
+	basis, err := HiddenNumberBasis(n, sigs, bits)
+	if err != nil {
+		return nil, err
+	}
+	reduced := LLL(basis, big.NewRat(99, 100))
+	m := len(sigs)
+	cu := basis[m+1][m+1]
+	neg := new(big.Rat).Neg(cu)
+	shift := new(big.Rat).SetInt(new(big.Int).Lsh(bigOne, bits))
+	for _, row := range reduced {
+		var dt *big.Rat
+		switch {
+		case row[m+1].Cmp(neg) == 0:
+			dt = new(big.Rat).Set(row[m])
+		case row[m+1].Cmp(cu) == 0:
+			dt = new(big.Rat).Neg(row[m])
+		default:
+			continue
+		}
+		if dt.Mul(dt, shift); !dt.IsInt() {
+			continue
+		}
+		d := new(big.Int).Mod(dt.Num(), n)
+		if pub.Curve.ScalarMult(pub.Curve.G, d).Equal(pub.Q) {
+			return d, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// GCMMessage is a GCM ciphertext with its additional data and tag.
+type GCMMessage struct {
+	AD, Ciphertext, Tag []byte
+}
+
+// ghashPoly returns GHASH(ad, ciphertext) + tag as a polynomial in H. With GHASH blocks b_1 .. b_n it
+// is b_1 H^n + ... + b_n H + tag, whose constant term differs from E(J0) only by the tag.
+func ghashPoly(m GCMMessage) GFPoly {
+	blocks := ghashBlocks(m.AD, m.Ciphertext)
+	p := make(GFPoly, len(blocks)+1)
+	p[0] = GF128FromBytes(m.Tag)
+	for i, b := range blocks {
+		p[len(blocks)-i] = b
+	}
+	return p.norm()
+}
+
+// RecoverGHASHKey returns the candidates for the GHASH key H from two or more messages that reused a
+// nonce. Each tag is GHASH_H(ad, c) + E(J0), so H is a root of the sum of the first message's GHASH
+// polynomial with each other's; further messages narrow the candidates through the common GCD.
+func RecoverGHASHKey(msgs ...GCMMessage) ([]GF128, error) {
+	if len(msgs) < 2 {
+		return nil, errors.New("need at least two messages")
+	}
+	first := ghashPoly(msgs[0])
+	var p GFPoly
+	for i, m := range msgs[1:] {
+		q := first.Add(ghashPoly(m))
+		if q.Degree() < 1 {
+			return nil, errors.Errorf("message %d is identical to the first", i+1)
+		}
+		if p == nil {
+			p = q
+		} else {
+			p = p.GCD(q)
+		}
+	}
+	roots, err := p.Roots()
+	if err != nil {
+		return nil, err
+	}
+	if len(roots) == 0 {
+		return nil, ErrNotFound
+	}
+	return roots, nil
+}
+
+// ForgeGCMTag returns the tag of (ad, ciphertext) under the key and nonce of known, given the GHASH key h.
+// E(J0) is recovered as known.Tag + GHASH_h(known.AD, known.Ciphertext).
+func ForgeGCMTag(h GF128, known GCMMessage, ad, ciphertext []byte) []byte {
+	s := GF128FromBytes(known.Tag).Add(GHASH(h, known.AD, known.Ciphertext))
+	return GHASH(h, ad, ciphertext).Add(s).Bytes()
+}
@@ -0,0 +1,414 @@
+package cryptopals
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestChallenge57Group(t *testing.T) {
+	g := Challenge57Group
+	if new(big.Int).Mul(g.Q, g.Cofactor()).Cmp(new(big.Int).Sub(g.P, bigOne)) != 0 {
+		t.Error("q does not divide p-1")
+	}
+	if new(big.Int).Exp(g.G, g.Q, g.P).Cmp(bigOne) != 0 {
+		t.Error("g does not have order q")
+	}
+}
+
+func TestSmallFactors(t *testing.T) {
+	tests := []struct {
+		n     int64
+		bound uint64
+		want  []int64
+	}{
+		{n: 1, bound: 100},
+		{n: 2 * 2 * 2 * 3 * 97, bound: 100, want: []int64{2, 3, 97}},
+		{n: 2 * 3 * 101, bound: 100, want: []int64{2, 3}},
+		{n: 7919, bound: 1 << 16, want: []int64{7919}},
+	}
+	for _, tt := range tests {
+		got := SmallFactors(big.NewInt(tt.n), tt.bound)
+		if len(got) != len(tt.want) {
+			t.Errorf("SmallFactors(%d, %d) = %v, want %v", tt.n, tt.bound, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i].Int64() != tt.want[i] {
+				t.Errorf("SmallFactors(%d, %d) = %v, want %v", tt.n, tt.bound, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestElementOfOrder(t *testing.T) {
+	p := Challenge57Group.P
+	for _, r := range SmallFactors(Challenge57Group.Cofactor(), 1<<10) {
+		h, err := ElementOfOrder(p, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Cmp(bigOne) == 0 || new(big.Int).Exp(h, r, p).Cmp(bigOne) != 0 {
+			t.Errorf("ElementOfOrder(p, %v) = %v does not have order %v", r, h, r)
+		}
+	}
+	if _, err := ElementOfOrder(p, big.NewInt(7)); err == nil {
+		t.Error("ElementOfOrder(p, 7) succeeded, but 7 does not divide p-1")
+	}
+}
+
+func TestRecoverDHKeySmallSubgroup(t *testing.T) {
+	bob, err := NewDHBob(Challenge57Group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := RecoverDHKeySmallSubgroup(Challenge57Group, bob, 1<<16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if y := new(big.Int).Exp(Challenge57Group.G, x, Challenge57Group.P); y.Cmp(bob.PublicKey()) != 0 {
+		t.Errorf("recovered key %v does not match Bob's public key", x)
+	}
+	if _, err := RecoverDHKeySmallSubgroup(Challenge57Group, bob, 1<<10); err == nil {
+		t.Error("RecoverDHKeySmallSubgroup() succeeded with too few small factors")
+	}
+}
+
+func ExampleChallenge57() {
+	bob := NewDHBobWithKey(Challenge57Group, mustInt("123456789012345678901234567890", 10))
+	x, err := RecoverDHKeySmallSubgroup(Challenge57Group, bob, 1<<16)
+	fmt.Println(x, bob.Calls(), err)
+	// output:
+	// 123456789012345678901234567890 12 <nil>
+}
+
+func TestRecoverDHKeyWithKangaroo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping kangaroo attack in short mode")
+	}
+	bob, err := NewDHBob(Challenge58Group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := RecoverDHKeyWithKangaroo(Challenge58Group, bob, bob.PublicKey(), 1<<16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if y := new(big.Int).Exp(Challenge58Group.G, x, Challenge58Group.P); y.Cmp(bob.PublicKey()) != 0 {
+		t.Errorf("recovered key %v does not match Bob's public key", x)
+	}
+	if got := bob.Calls(); got != 7 {
+		t.Errorf("oracle called %d times, want 7", got)
+	}
+}
+
+func ExampleChallenge58() {
+	g := Challenge58Group
+	kg := &Kangaroo{Group: ModPGroup{P: g.P}}
+	y := mustInt("7760073848032689505395005705677365876654629189298052775754597607446617558600394076764814236081991643094239886772481052254010323780165093955236429914607119", 10)
+	x, err := kg.Catch(g.G, y, big.NewInt(0), big.NewInt(1<<20))
+	fmt.Println(x, err)
+	// output:
+	// 705485 <nil>
+}
+
+func TestRecoverECDHKeyInvalidCurve(t *testing.T) {
+	bob, err := NewECDHBob(Challenge59Curve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := RecoverECDHKeyInvalidCurve(Challenge59Curve, bob, Challenge59InvalidCurves, 1<<16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Challenge59Curve.ScalarMult(Challenge59Curve.G, d); !got.Equal(bob.PublicKey()) {
+		t.Errorf("recovered key %v does not match Bob's public key", d)
+	}
+
+	bob.Validate = true
+	if _, err := RecoverECDHKeyInvalidCurve(Challenge59Curve, bob, Challenge59InvalidCurves, 1<<16); err != ErrInvalidPoint {
+		t.Errorf("attack on validating Bob: error = %v, want %v", err, ErrInvalidPoint)
+	}
+}
+
+func ExampleChallenge59() {
+	bob := NewECDHBobWithKey(Challenge59Curve, mustInt("12345678901234567890123456789", 10))
+	d, err := RecoverECDHKeyInvalidCurve(Challenge59Curve, bob, Challenge59InvalidCurves, 1<<16)
+	fmt.Println(d, err)
+	// output:
+	// 12345678901234567890123456789 <nil>
+}
+
+func TestTwistLeak(t *testing.T) {
+	c := Challenge60Curve
+	bob, err := NewXECDHBob(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, R, err := TwistLeak(c, bob, 1<<18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := big.NewInt(11 * 107 * 197 * 1621 * 105143); R.Cmp(want) != 0 {
+		t.Errorf("TwistLeak() modulus = %v, want %v", R, want)
+	}
+	d := new(big.Int).Mod(bob.d, R)
+	if d.Cmp(x) != 0 && d.Cmp(new(big.Int).Sub(R, x)) != 0 {
+		t.Errorf("TwistLeak() = %v mod %v, want ±%v", x, R, d)
+	}
+}
+
+func TestRecoverXECDHKeyTwist(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping twist attack in short mode")
+	}
+	c := Challenge60Curve
+	bob, err := NewXECDHBob(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := RecoverXECDHKeyTwist(c, bob, bob.PublicKey(), 1<<22)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Ladder(c.U, d); got.Cmp(bob.PublicKey()) != 0 {
+		t.Errorf("recovered key %v does not match Bob's public key", d)
+	}
+}
+
+func TestForgeECDSAKey(t *testing.T) {
+	k, err := GenerateECDSAKey(Challenge59Curve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed := sha256.Sum256([]byte("I hereby transfer all my money to Mallory"))
+	r, s, err := k.Sign(hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := ForgeECDSAKey(&k.ECDSAPublicKey, hashed[:], r, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forged.Q.Equal(k.Q) {
+		t.Error("ForgeECDSAKey() returned the original public key")
+	}
+	if !VerifyECDSA(&forged.ECDSAPublicKey, hashed[:], r, s) {
+		t.Error("signature does not verify under the forged key")
+	}
+	if !forged.Curve.IsOnCurve(forged.Curve.G) || !forged.Curve.ScalarMult(forged.Curve.G, forged.Curve.N).IsIdentity() {
+		t.Error("forged base point is not a point of order N on the curve")
+	}
+	r2, s2, err := forged.Sign(hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyECDSA(&forged.ECDSAPublicKey, hashed[:], r2, s2) {
+		t.Error("forged key cannot sign")
+	}
+}
+
+func TestSmoothPrime(t *testing.T) {
+	// Whenever two bits remain, 3 is the only fresh candidate; avoiding it must not hang.
+	avoid := map[uint64]bool{3: true, 5: true, 7: true}
+	for i := 0; i < 20; i++ {
+		p, factors, err := smoothPrime(256, avoid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.BitLen() != 256 || !p.ProbablyPrime(20) {
+			t.Fatalf("smoothPrime() = %v, want a 256-bit prime", p)
+		}
+		prod := big.NewInt(1)
+		for _, r := range factors {
+			if avoid[r.Uint64()] {
+				t.Errorf("smoothPrime() used avoided factor %v", r)
+			}
+			prod.Mul(prod, r)
+		}
+		if prod.Add(prod, bigOne).Cmp(p) != 0 {
+			t.Errorf("factors %v do not multiply to p-1", factors)
+		}
+	}
+}
+
+func TestPohligHellman(t *testing.T) {
+	p, factors, err := smoothPrime(128, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := big.NewInt(2)
+	for !isPrimitiveRoot(g, p, factors) {
+		g.Add(g, bigOne)
+	}
+	x := mustInt("123456789012345678901234567", 10)
+	y := new(big.Int).Exp(g, x, p)
+	got, err := PohligHellman(g, y, p, factors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if new(big.Int).Exp(g, got, p).Cmp(y) != 0 {
+		t.Errorf("PohligHellman() = %v, want a logarithm of %v", got, y)
+	}
+	// g^2 generates only half the group, so g is not one of its powers.
+	g2 := new(big.Int).Exp(g, big.NewInt(2), p)
+	if _, err := PohligHellman(g2, g, p, factors); err != ErrNotFound {
+		t.Errorf("PohligHellman() error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestForgeRSAKey(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping smooth prime search in short mode")
+	}
+	k, err := GenerateKey(512, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed := sha256.Sum256([]byte("I hereby transfer all my money to Mallory"))
+	sig, err := k.SignPKCS1v15(crypto.SHA256, hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	forged, search, err := ForgeRSAKey(ctx, k.Public(), crypto.SHA256, hashed[:], sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("smooth prime search took %v", search)
+	if forged.N.Cmp(k.N) == 0 {
+		t.Error("ForgeRSAKey() returned the original modulus")
+	}
+	if err := VerifyPKCS1v15(forged.Public(), crypto.SHA256, hashed[:], sig); err != nil {
+		t.Errorf("signature does not verify under the forged key: %v", err)
+	}
+	sig2, err := forged.SignPKCS1v15(crypto.SHA256, hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sig2, sig) {
+		t.Error("forged key signs differently")
+	}
+	if search <= 0 {
+		t.Errorf("search time = %v, want > 0", search)
+	}
+}
+
+func TestForgeRSAKeyCanceled(t *testing.T) {
+	k, err := GenerateKey(512, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed := sha256.Sum256([]byte("I hereby transfer all my money to Mallory"))
+	sig, err := k.SignPKCS1v15(crypto.SHA256, hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := ForgeRSAKey(ctx, k.Public(), crypto.SHA256, hashed[:], sig); err != context.Canceled {
+		t.Errorf("ForgeRSAKey() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestRecoverECDSAKeyBiasedNonces(t *testing.T) {
+	// With 8 known bits per nonce and a 125-bit group order, 24 signatures put the hidden vector well
+	// below the expected shortest vector of an unrelated lattice of the same volume.
+	k, err := GenerateECDSAKey(Challenge59Curve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := &BiasedNonceSigner{Key: k, Bits: 8}
+	var sigs []ECDSASignature
+	for i := 0; i < 24; i++ {
+		hashed := sha256.Sum256([]byte(fmt.Sprintf("message %d", i)))
+		sig, err := signer.Sign(hashed[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !VerifyECDSA(&k.ECDSAPublicKey, hashed[:], sig.R, sig.S) {
+			t.Fatalf("signature %d does not verify", i)
+		}
+		sigs = append(sigs, sig)
+	}
+	d, err := RecoverECDSAKeyBiasedNonces(&k.ECDSAPublicKey, sigs, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Cmp(k.D) != 0 {
+		t.Errorf("RecoverECDSAKeyBiasedNonces() = %v, want %v", d, k.D)
+	}
+	if _, err := RecoverECDSAKeyBiasedNonces(&k.ECDSAPublicKey, sigs[:2], 8); err != ErrNotFound {
+		t.Errorf("RecoverECDSAKeyBiasedNonces() with 2 signatures: error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestGCMForbiddenAttack(t *testing.T) {
+	block, err := aes.NewCipher(RandomNBytes(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ours, err := NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := RandomNBytes(GCMNonceSize)
+	seal := func(ad, pt string) GCMMessage {
+		sealed := gcm.Seal(nil, nonce, []byte(pt), []byte(ad))
+		n := len(sealed) - GCMTagSize
+		return GCMMessage{AD: []byte(ad), Ciphertext: sealed[:n], Tag: sealed[n:]}
+	}
+	a := seal("from: alice", "transfer 100 to bob, regards alice")
+	b := seal("from: carol", "transfer 5 to dave")
+	c := seal("", "a third message under the same nonce")
+
+	hs, err := RecoverGHASHKey(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, h := range hs {
+		found = found || h == ours.H()
+	}
+	if !found {
+		t.Fatalf("RecoverGHASHKey() = %v, missing H = %v", hs, ours.H())
+	}
+	hs, err = RecoverGHASHKey(a, b, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hs) != 1 || hs[0] != ours.H() {
+		t.Fatalf("RecoverGHASHKey() with three messages = %v, want [%v]", hs, ours.H())
+	}
+
+	// Knowing the plaintext of a, flip it to a transfer of 900 to mallory.
+	forged := append([]byte{}, a.Ciphertext...)
+	for i, m := range []byte("transfer 900 to mal") {
+		forged[i] ^= "transfer 100 to bob"[i] ^ m
+	}
+	ad := []byte("from: alice")
+	tag := ForgeGCMTag(hs[0], a, ad, forged)
+	pt, err := gcm.Open(nil, nonce, append(forged, tag...), ad)
+	if err != nil {
+		t.Fatalf("crypto/cipher rejected the forgery: %v", err)
+	}
+	if want := "transfer 900 to mal, regards alice"; string(pt) != want {
+		t.Errorf("forged plaintext = %q, want %q", pt, want)
+	}
+	if _, err := RecoverGHASHKey(a); err == nil {
+		t.Error("RecoverGHASHKey() succeeded with one message")
+	}
+}